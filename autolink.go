@@ -0,0 +1,121 @@
+package runic
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// urlSchemes begin the bare URLs linked by `WithAutoLinks`
+var urlSchemes = []string{"https://", "http://"}
+
+// urlTrailingPunctuation is left out of the end of a bare URL, being more
+// likely to end the sentence than the URL
+const urlTrailingPunctuation = `.,:;!?'"`
+
+// linkURLs replaces each bare URL in the text below n with a link to it, as in
+// `see https://example.com.`. the content of links, and of images whose alt
+// text can't hold one, is left alone
+func linkURLs(n *Node, cfg config) {
+	var children []*Node
+	for _, child := range n.Children {
+		if child.Typ == nodeText {
+			children = append(children, splitURLs(child)...)
+			continue
+		}
+		if !isLinkContent(child, cfg) {
+			linkURLs(child, cfg)
+		}
+		children = append(children, child)
+	}
+
+	n.Children = nil
+	for _, child := range children {
+		n.AppendChild(child)
+	}
+}
+
+// isLinkContent reports whether the children of n are the content of a link or
+// image, including a link which is missing its url
+func isLinkContent(n *Node, cfg config) bool {
+	if n.Typ == nodeError {
+		message, _ := errorParts(n.Val, cfg)
+		return message == errInvalidLink || message == errInvalidImage
+	}
+	return n.Typ == nodeLinkTag || n.Typ == nodeImageTag
+}
+
+// splitURLs splits a text node into text and a link for each URL in it. like
+// a tag, each piece is joined to the one before it unless a space separates
+// them, and the first piece is joined wherever the text node was
+func splitURLs(text *Node) []*Node {
+	var pieces []*Node
+	joined := text.Joined
+	addPiece := func(n *Node) {
+		n.Line, n.Pos = text.Line, text.Pos
+		n.Joined = joined
+		pieces = append(pieces, n)
+	}
+
+	rest := text.Val
+	for {
+		start, end := findURL(rest)
+		if start < 0 {
+			break
+		}
+		before := strings.TrimRight(rest[:start], " ")
+		if before != "" {
+			addPiece(newNode(nodeText, before))
+		}
+		joined = len(before) == start && (before != "" || joined)
+		url := rest[start:end]
+		addPiece(newNode(nodeLinkTag, url, newNode(nodeText, url)))
+		after := strings.TrimLeft(rest[end:], " ")
+		joined = len(after) == len(rest[end:])
+		rest = after
+	}
+
+	if pieces == nil {
+		return []*Node{text}
+	}
+	if rest != "" {
+		addPiece(newNode(nodeText, rest))
+	}
+	return pieces
+}
+
+// findURL returns the offsets of the first bare URL in the text, or -1 if there
+// isn't one. a URL runs up to the next space, leaving out punctuation ending it
+// and a closing parenthesis it doesn't open, as in `(see https://x.com)`
+func findURL(text string) (start, end int) {
+	for offset := 0; offset < len(text); {
+		start = -1
+		for _, scheme := range urlSchemes {
+			if i := strings.Index(text[offset:], scheme); i >= 0 && (start < 0 || offset+i < start) {
+				start = offset + i
+			}
+		}
+		if start < 0 {
+			return -1, -1
+		}
+
+		end = start + strings.IndexByte(text[start:]+" ", ' ')
+		for end > start {
+			url := text[start:end]
+			unopened := url[len(url)-1] == charClosingParen && strings.Count(url, "(") < strings.Count(url, ")")
+			if !strings.ContainsAny(url[len(url)-1:], urlTrailingPunctuation) && !unopened {
+				break
+			}
+			end--
+		}
+
+		// the scheme must begin a word, and be followed by a host
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		host := strings.Index(text[start:end], "://") + len("://")
+		if !unicode.IsLetter(before) && !unicode.IsDigit(before) && start+host < end {
+			return start, end
+		}
+		offset = start + 1
+	}
+	return -1, -1
+}
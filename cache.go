@@ -0,0 +1,82 @@
+package runic
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// htmlCache is a least recently used cache of rendered HTML, keyed by a hash
+// of the input text. it is safe for concurrent use
+type htmlCache struct {
+	mu      sync.Mutex
+	size    int                                 // maximum number of entries
+	entries map[[sha256.Size]byte]*list.Element // entries by key
+	order   *list.List                          // entries from most to least recently used
+}
+
+type htmlCacheEntry struct {
+	key  [sha256.Size]byte
+	html string
+}
+
+func newHtmlCache(size int) *htmlCache {
+	return &htmlCache{
+		size:    size,
+		entries: map[[sha256.Size]byte]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *htmlCache) get(key [sha256.Size]byte) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*htmlCacheEntry).html, true
+}
+
+func (c *htmlCache) add(key [sha256.Size]byte, html string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&htmlCacheEntry{key: key, html: html})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*htmlCacheEntry).key)
+	}
+}
+
+func (c *htmlCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func sha256Key(input string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(input))
+}
+
+// HtmlCached returns the same output as `Html`, reusing the result of earlier
+// calls with the same input. the cache belongs to the parser, so entries are
+// always rendered with its options. unlike `Html`, it is safe to call
+// concurrently. the number of cached documents is set by `WithCacheSize`
+func (p *parser) HtmlCached(input string) string {
+	if p.cache == nil {
+		return (&parser{config: p.config}).Html(input)
+	}
+	key := sha256Key(input)
+	if html, ok := p.cache.get(key); ok {
+		return html
+	}
+	html := (&parser{config: p.config}).Html(input)
+	p.cache.add(key, html)
+	return html
+}
@@ -0,0 +1,76 @@
+package runic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestHtmlCached(t *testing.T) {
+	testParser := New(WithCacheSize(2))
+	inputs := []string{
+		". This is a level one heading",
+		"The quick bold[brown fox] jumps over the lazy dog",
+		"- Item one\n- Item two",
+	}
+
+	for _, input := range inputs[:2] {
+		expectedHtml := New().Html(input)
+		for range 2 {
+			if htmlString := testParser.HtmlCached(input); htmlString != expectedHtml {
+				t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", input, expectedHtml, htmlString)
+			}
+		}
+	}
+	if n := testParser.cache.len(); n != 2 {
+		t.Errorf("expected 2 cached documents, found %d", n)
+	}
+
+	// the heading is now the least recently used and is evicted
+	testParser.HtmlCached(inputs[1])
+	testParser.HtmlCached(inputs[2])
+	if n := testParser.cache.len(); n != 2 {
+		t.Errorf("expected 2 cached documents, found %d", n)
+	}
+	for i, expectedCached := range []bool{false, true, true} {
+		key := sha256Key(inputs[i])
+		if _, cached := testParser.cache.get(key); cached != expectedCached {
+			t.Errorf("%q ERROR\nexpected cached: %t", inputs[i], expectedCached)
+		}
+	}
+}
+
+func TestHtmlCachedDisabled(t *testing.T) {
+	testParser := New(WithCacheSize(0))
+	input := "The quick bold[brown fox] jumps over the lazy dog"
+	if htmlString, expectedHtml := testParser.HtmlCached(input), New().Html(input); htmlString != expectedHtml {
+		t.Errorf("expected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+}
+
+func TestHtmlCachedConcurrent(t *testing.T) {
+	testParser := New(WithCacheSize(4))
+	inputs := []string{
+		". This is a level one heading",
+		"The quick bold[brown fox] jumps over the lazy dog",
+		"- Item one\n  - Item two",
+		"The quick brown fox\n\njumps over the lazy dog",
+		"italic[quick]",
+	}
+	var expectedHtml []string
+	for _, input := range inputs {
+		expectedHtml = append(expectedHtml, New().Html(input))
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := i % len(inputs)
+			if htmlString := testParser.HtmlCached(inputs[n]); htmlString != expectedHtml[n] {
+				t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", inputs[n], expectedHtml[n], htmlString)
+			}
+		}()
+	}
+	wg.Wait()
+}
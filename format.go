@@ -0,0 +1,436 @@
+package runic
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToRunic writes a tree returned by `Parse` back out as canonical runic
+// markup, so that parsing the result gives the same tree. blocks are separated
+// by the newlines ending a paragraph, lists are indented by `WithIndentUnit`
+// per level, and text is escaped wherever it would otherwise be read as
+// markup
+func (p *parser) ToRunic(tree *Node) string {
+	return renderRunic(tree, p.config)
+}
+
+// Canonical rewrites the input as canonical runic markup, which is the same
+// for any two inputs parsing to the same tree. blocks are separated by one
+// blank line, runs of whitespace are collapsed and lists are indented evenly,
+// so it can be used to format documents for stable diffs. the frontmatter and
+// comments, which the tree leaves out, are kept between the blocks they were
+// found between
+func (p *parser) Canonical(input string) string {
+	return renderRunic(p.Parse(input), p.config, asides(input, p.config)...)
+}
+
+// asides returns the frontmatter and comment tokens of the input, which are
+// left out of the tree
+func asides(input string, cfg config) []token {
+	lexer := lex(input, cfg)
+	tokens := []token{}
+	for lexer.nextToken() {
+		if lexer.token.Typ == typeFrontmatter || lexer.token.Typ == typeComment {
+			tokens = append(tokens, lexer.token)
+		}
+	}
+	return tokens
+}
+
+// renderRunic writes the given tree as runic markup, depending on nothing
+// beyond its arguments. any asides are written as blocks of their own, before
+// the first block beginning after them
+func renderRunic(tree *Node, cfg config, asides ...token) string {
+	if tree.Typ != nodeRoot {
+		return runicBlock(tree, cfg)
+	}
+
+	blockBreak := strings.Repeat(string(charNewline), max(cfg.paragraphBreak, 1))
+	var b strings.Builder
+	writeAsides := func(before int) bool {
+		comment := false
+		written := false
+		for len(asides) > 0 && asides[0].Pos < before {
+			// comments between the same two blocks are kept together
+			if comment && asides[0].Typ == typeComment {
+				b.WriteRune(charNewline)
+			} else if b.Len() > 0 {
+				b.WriteString(blockBreak)
+			}
+			b.WriteString(strings.TrimRightFunc(asides[0].Val, unicode.IsSpace))
+			comment = asides[0].Typ == typeComment
+			asides = asides[1:]
+			written = true
+		}
+		return written
+	}
+	for i, child := range tree.Children {
+		split := writeAsides(child.Pos)
+		// an empty paragraph is only kept by `WithPreserveEmptyParagraphs`, which
+		// reads it back from each newline beyond the break
+		if child.Typ == nodeParagraph && len(child.Children) == 0 {
+			if cfg.emptyParagraphs && i > 0 && i < len(tree.Children)-1 {
+				b.WriteRune(charNewline)
+				continue
+			}
+			// otherwise it can only have come from a preserved `paragraph[]`
+			if cfg.emptyParagraphs || cfg.emptyTags != EmptyTagsPreserve {
+				continue
+			}
+		}
+		// a block of nothing but escaped whitespace is trimmed away, so it's left
+		// out as well
+		block := runicBlock(child, cfg)
+		if block == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(blockBreak)
+		}
+		// lists of the same type would otherwise be read as one loose list, so
+		// a comment is written between them, as they can only be split by one
+		if i > 0 && !split && isOneOf(child.Typ, nodeList, nodeOrderedList) && tree.Children[i-1].Typ == child.Typ {
+			b.WriteString(commentMarker + blockBreak)
+		}
+		// a `---` rule beginning the input would be read as opening frontmatter
+		if child.Typ == nodeRule && b.Len() == 0 {
+			b.WriteString(strings.Repeat(string(charEquals), ruleMinLength))
+			continue
+		}
+		b.WriteString(block)
+	}
+	writeAsides(math.MaxInt)
+	return b.String()
+}
+
+// runicBlock writes a single block, such as a heading or a list
+func runicBlock(n *Node, cfg config) string {
+	switch n.Typ {
+	case nodeHeadingOne, nodeHeadingTwo, nodeHeadingThree, nodeHeadingFour, nodeHeadingFive, nodeHeadingSix:
+		return runicPrefixed(n.Val, runicInline(n.Children, cfg, false))
+	case nodeList, nodeOrderedList:
+		return runicList(n, cfg)
+	case nodeBlockquote:
+		lines := []string{}
+		for i, paragraph := range n.Children {
+			if i > 0 {
+				lines = append(lines, string(charQuote))
+			}
+			lines = append(lines, runicPrefixed(string(charQuote), runicInline(paragraph.Children, cfg, false)))
+		}
+		if len(lines) == 0 {
+			return string(charQuote)
+		}
+		return strings.Join(lines, string(charNewline))
+	case nodeRule:
+		return strings.Repeat(string(charHyphen), ruleMinLength)
+	case nodeParagraph:
+		if len(n.Children) == 0 {
+			return tagParagraph + "[]"
+		}
+		return escapeBlockStart(runicInline(n.Children, cfg, false))
+	case nodeError:
+		// an invalid heading takes the place of the heading it was parsed from
+		if message, detail := errorParts(n.Val, cfg); message == errInvalidHeading {
+			return runicPrefixed(detail, runicInline(n.Children, cfg, false))
+		}
+	}
+	return escapeBlockStart(runicInline([]*Node{n}, cfg, false))
+}
+
+// runicList writes each item of a list on its own line, with the lists nested
+// in an item indented beneath it. the items of a loose list are separated by a
+// blank line
+func runicList(list *Node, cfg config) string {
+	indent := strings.Repeat(cfg.indentUnit, list.Depth)
+	var b strings.Builder
+	for i, item := range list.Children {
+		marker := string(charHyphen)
+		// items are numbered in order unless they kept their own number
+		if list.Typ == nodeOrderedList {
+			number := max(list.Start, 1) + i
+			if item.Number > 0 {
+				number = item.Number
+			}
+			marker = strconv.Itoa(number) + string(charDot)
+		}
+		if b.Len() > 0 {
+			b.WriteRune(charNewline)
+			if list.Loose {
+				b.WriteRune(charNewline)
+			}
+		}
+		if item.Checked {
+			marker += " " + checkboxChecked
+		} else if item.Task {
+			marker += " " + checkboxUnchecked
+		}
+
+		content, nested := splitNestedLists(item)
+		b.WriteString(indent + runicPrefixed(marker, runicInline(content, cfg, false)))
+		for j, nestedList := range nested {
+			b.WriteRune(charNewline)
+			// a loose list sets its nested lists apart from the item's content
+			// too, which alone is enough to make it loose
+			if list.Loose && j == 0 {
+				b.WriteRune(charNewline)
+			}
+			b.WriteString(runicList(nestedList, cfg))
+		}
+	}
+	return b.String()
+}
+
+// runicPrefixed writes a block marker followed by the block's content, if it
+// has any
+func runicPrefixed(marker, content string) string {
+	if content == "" {
+		return marker
+	}
+	return marker + " " + content
+}
+
+// runicInline writes a run of inline nodes, separated by a space unless a node
+// is joined to the one before it. whitespace at either end of the run or
+// before a line break is never read, so it is left out
+func runicInline(nodes []*Node, cfg config, inCondition bool) string {
+	var b strings.Builder
+	// the end of the last node other than a line break
+	end := 0
+	for i, n := range nodes {
+		// the builder doesn't copy its content to return it
+		s := b.String()
+		lineStart := strings.HasSuffix(s, string(charNewline))
+		// the marker is written straight after the text it ends, so it can't be
+		// read as part of an escape. `WithHardLineBreaks` reads the newline
+		// alone, unless it would begin a line and be read as a blank one
+		if n.Typ == nodeLineBreak {
+			if !cfg.hardLineBreaks || s == "" || lineStart {
+				b.WriteString(lineBreakMarker)
+			}
+			b.WriteRune(charNewline)
+			continue
+		}
+
+		node := ""
+		switch n.Typ {
+		case nodeText:
+			text := n.Val
+			if s == "" || strings.HasSuffix(s, " ") || lineStart {
+				text = strings.TrimLeftFunc(text, unicode.IsSpace)
+			}
+			if i == len(nodes)-1 || nodes[i+1].Typ == nodeLineBreak {
+				text = strings.TrimRightFunc(text, unicode.IsSpace)
+			}
+			node = escapeRunic(text, cfg, inCondition)
+			// a curly directly after a condition's flag would open its block
+			if n.Joined && !inCondition && strings.HasPrefix(node, string(charOpeningCurly)) {
+				node = string(charBackslash) + node
+			}
+			// as would a rule or a comment on the line after a line break
+			if lineStart && isBlockEnd(node) {
+				node = string(charBackslash) + node
+			}
+		case nodeBoldTag:
+			node = runicTag("bold", n, cfg, inCondition)
+		case nodeItalicTag:
+			node = runicTag("italic", n, cfg, inCondition)
+		case nodeUnderlineTag:
+			node = runicTag("underline", n, cfg, inCondition)
+		case nodeKbdTag:
+			node = runicTag("kbd", n, cfg, inCondition)
+		case nodeMarkTag:
+			node = runicTag("mark", n, cfg, inCondition)
+		case nodeCustomTag:
+			node = runicTag(n.Val, n, cfg, inCondition)
+		case nodeStrikeTag:
+			node = runicTag("strike", n, cfg, inCondition)
+		case nodeLinkTag:
+			node = runicTag(tagLink+"("+n.Val+")", n, cfg, inCondition)
+		case nodeImageTag:
+			node = runicTag(tagImage+"("+n.Val+")", n, cfg, inCondition)
+		case nodeColorTag:
+			node = runicTag(tagColor+"("+n.Val+")", n, cfg, inCondition)
+		case nodeCodeTag:
+			node = tagCode + "[" + balanceCode(n.Val) + "]"
+		case nodeVariableTag:
+			node = tagVariable + "[" + escapeRunic(n.Val, cfg, inCondition) + "]"
+		case nodeConditionTag:
+			node = tagCondition + "[" + escapeRunic(n.Val, cfg, false) + "]{" + runicContent(n.Children, cfg, true) + "}"
+		case nodeError:
+			node = runicError(n, cfg, inCondition)
+		}
+		if node == "" {
+			continue
+		}
+
+		// a tag name directly after a word would be read as part of it, and one
+		// beginning a line is only joined to the line break before it by an escape
+		last, _ := utf8.DecodeLastRuneInString(s)
+		if n.Joined && (unicode.IsLetter(last) || lineStart) {
+			b.WriteRune(charBackslash)
+		} else if s != "" && !n.Joined && !lineStart && !strings.HasSuffix(s, " ") && !strings.HasPrefix(node, " ") {
+			b.WriteRune(' ')
+		}
+		b.WriteString(node)
+		end = b.Len()
+	}
+	// line breaks with nothing written after them wouldn't be read back as
+	// breaks, so they're left out
+	return b.String()[:end]
+}
+
+// runicTag writes a tag and its content
+func runicTag(name string, n *Node, cfg config, inCondition bool) string {
+	return name + "[" + runicContent(n.Children, cfg, inCondition) + "]"
+}
+
+// runicContent writes the content of a tag. a tag as the first node is joined
+// to the opening square unless a space separates them
+func runicContent(nodes []*Node, cfg config, inCondition bool) string {
+	s := runicInline(nodes, cfg, inCondition)
+	if len(nodes) > 0 && nodes[0].Typ != nodeText && !nodes[0].Joined {
+		s = " " + s
+	}
+	return s
+}
+
+// runicError writes an error node back out as markup which is parsed into the
+// same error
+func runicError(n *Node, cfg config, inCondition bool) string {
+	message, detail := errorParts(n.Val, cfg)
+	content := runicContent(n.Children, cfg, inCondition)
+	switch message {
+	case errInvalidTag, errEmptyTag:
+		return detail + "[" + content + "]"
+	// the url is kept when its scheme isn't allowed
+	case errInvalidLink:
+		return runicTagArg(tagLink, detail) + "[" + content + "]"
+	case errInvalidImage:
+		return runicTagArg(tagImage, detail) + "[" + content + "]"
+	case errInvalidColor:
+		return tagColor + "[" + content + "]"
+	case errInvalidVariable:
+		return tagVariable + "[" + content + "]"
+	case errInvalidCondition:
+		// a flag without a block, or a block without a flag
+		if len(n.Children) == 0 {
+			return tagCondition + "[" + escapeRunic(strings.TrimSpace(detail), cfg, inCondition) + "]"
+		}
+		if detail == "" {
+			return tagCondition + "[]{" + runicContent(n.Children, cfg, true) + "}"
+		}
+		return tagCondition + "[" + content + "]"
+	}
+	return content
+}
+
+// balanceCode closes the squares left open in the value of a code tag, which
+// is kept as written and so can only have any open when its block ended the
+// code. a backslash ending the value would escape the closing square
+func balanceCode(code string) string {
+	depth := 0
+	for i, char := range code {
+		if i > 0 && code[i-1] == charBackslash {
+			continue
+		}
+		switch char {
+		case charOpeningSquare:
+			depth++
+		case charClosingSquare:
+			depth--
+		}
+	}
+	if strings.HasSuffix(code, string(charBackslash)) {
+		code += " "
+	}
+	return code + strings.Repeat(string(charClosingSquare), max(depth, 0))
+}
+
+// runicTagArg writes the name of a tag followed by its argument, if it has one
+func runicTagArg(name, arg string) string {
+	if arg == "" {
+		return name
+	}
+	return name + "(" + arg + ")"
+}
+
+// errorParts splits the value of an error node into its message and detail,
+// in either of the formats written by `errorValue`
+func errorParts(val string, cfg config) (message, detail string) {
+	if cfg.stableErrors {
+		parts := strings.SplitN(val, "|", 3)
+		if len(parts) == 3 {
+			return parts[1], parts[2]
+		}
+		return "", ""
+	}
+	for message := range errorCodes {
+		if detail, ok := strings.CutPrefix(val, message+": "); ok {
+			return message, detail
+		}
+	}
+	return "", ""
+}
+
+// escapeRunic escapes the characters of a text node which would otherwise be
+// read as markup, and collapses any run of whitespace into a single space as
+// the lexer does, unless `WithPreserveSpaces` keeps runs of spaces. `}` only
+// ends a condition from inside one, and `~` only matters to the strikethrough
+// shorthand
+func escapeRunic(s string, cfg config, inCondition bool) string {
+	var b strings.Builder
+	lastSpace := false
+	for i, char := range s {
+		if unicode.IsSpace(char) {
+			if !lastSpace || cfg.preserveSpaces && char == ' ' {
+				b.WriteRune(' ')
+			}
+			lastSpace = true
+			continue
+		}
+		lastSpace = false
+		switch {
+		case char == charBackslash:
+			// only the last backslash of a run is read as an escape, and it
+			// escapes the character after the run too
+			if !strings.HasPrefix(s[i+1:], string(charBackslash)) {
+				b.WriteRune(charBackslash)
+			}
+		case char == charOpeningSquare, char == charClosingSquare,
+			inCondition && (char == charOpeningCurly || char == charClosingCurly),
+			cfg.strikeShorthand && char == charTilde:
+			if !strings.HasSuffix(s[:i], string(charBackslash)) {
+				b.WriteRune(charBackslash)
+			}
+		}
+		b.WriteRune(char)
+	}
+	// a backslash ending the text would otherwise escape whatever follows it
+	if strings.HasSuffix(s, string(charBackslash)) {
+		b.WriteRune(' ')
+	}
+	return b.String()
+}
+
+// escapeBlockStart escapes the first character of a paragraph when it would
+// otherwise begin a heading, list, quote, comment, or rule
+func escapeBlockStart(s string) string {
+	if s == "" {
+		return s
+	}
+	if strings.HasPrefix(s, commentMarker) {
+		return string(charBackslash) + s
+	}
+	switch rune(s[0]) {
+	case charDot, charColon, charHyphen, charQuote:
+		return string(charBackslash) + s
+	}
+	if numberpointLength(s) > 0 || ruleLength(s) > 0 {
+		return string(charBackslash) + s
+	}
+	return s
+}
@@ -0,0 +1,315 @@
+package runic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type toRunicTest struct {
+	name          string
+	input         string
+	expectedRunic string
+}
+
+var toRunicTests = []toRunicTest{
+	{
+		"headings and paragraph",
+		". Title\n: Subtitle\nThe quick bold[brown] fox\njumps",
+		". Title\n\n: Subtitle\n\nThe quick bold[brown] fox jumps",
+	},
+	{
+		"nested list",
+		"- Item one\n  - Item two\n    - Item three\n- Item four",
+		"- Item one\n  - Item two\n    - Item three\n- Item four",
+	},
+	{
+		"task items",
+		"- [X]   Item one\n  - [ ] Item two\n- [x]\n- \\[x] not a task",
+		"- [x] Item one\n  - [ ] Item two\n- [x]\n- \\[x\\] not a task",
+	},
+	{
+		"ordered list renumbered",
+		"1. Item one\n3. Item two\n- Item three",
+		"1. Item one\n2. Item two\n\n- Item three",
+	},
+	{
+		"loose list",
+		"- Item one\n\n- Item two",
+		"- Item one\n\n- Item two",
+	},
+	{
+		"escaped squares and backslash",
+		`Square \[brackets\] and a \\ backslash`,
+		`Square \[brackets\] and a \\ backslash`,
+	},
+	{
+		"escaped heading char",
+		`\. not a heading`,
+		`\. not a heading`,
+	},
+	{
+		"escaped comment after line break",
+		"Para one\\\\\n\\// not a comment",
+		"Para one\\\\\n\\// not a comment",
+	},
+	{
+		"escaped bulletpoint",
+		`\- not a list`,
+		`\- not a list`,
+	},
+	{
+		"tag joined to text",
+		`The quick brown fox\bold[jumps over] the lazy dog`,
+		`The quick brown fox\bold[jumps over] the lazy dog`,
+	},
+	{
+		"quote",
+		"> Line one\n> Line two\n>\n> Line three",
+		"> Line one Line two\n>\n> Line three",
+	},
+	{
+		"tags w/ arguments and values",
+		"link(https://example.com)[docs] color(red)[warm] code[a [b] c] var[name]",
+		"link(https://example.com)[docs] color(red)[warm] code[a [b] c] var[name]",
+	},
+	{
+		"condition w/ escaped curly",
+		`if[draft]{bold[Draft] notes with a \} brace}`,
+		`if[draft]{bold[Draft] notes with a \} brace}`,
+	},
+	{
+		"error nodes",
+		"foo[bar] if[] var[]",
+		"foo[bar] if[] var[]",
+	},
+	{
+		"paragraph tags",
+		"Intro paragraph[Grouped] outro",
+		"Intro\n\nGrouped\n\noutro",
+	},
+	{
+		"line break",
+		"The quick  \\\\\n   bold[brown] fox\njumps",
+		"The quick\\\\\nbold[brown] fox jumps",
+	},
+	{
+		"line break beginning a line",
+		"\\\\\nThe quick\\\\\n\\\\\n\\bold[brown] fox",
+		"\\\\\nThe quick\\\\\n\\\\\n\\bold[brown] fox",
+	},
+	{
+		"tags w/ mixed case names",
+		"Bold[x] LINK(url)[y] Foo[z]",
+		"bold[x] link(url)[y] Foo[z]",
+	},
+	{
+		"unclosed tag",
+		"The bold[quick brown fox",
+		"The bold[quick brown fox]",
+	},
+	{
+		"loose list w/ nested list",
+		"- Item one\n\n  - Item two\n- Item three",
+		"- Item one\n\n  - Item two\n\n- Item three",
+	},
+	{
+		"rules",
+		"===\n\nRoses\n=====\nViolets\n\n\\---",
+		"===\n\nRoses\n\n---\n\nViolets\n\n\\---",
+	},
+}
+
+func TestToRunic(t *testing.T) {
+	for _, test := range toRunicTests {
+		testParser := New()
+		runic := testParser.ToRunic(testParser.Parse(test.input))
+		if runic != test.expectedRunic {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedRunic, runic)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+// positionlessJSON returns the tree as JSON without the position of each node,
+// which moves when the markup is rewritten
+func positionlessJSON(tree *Node) string {
+	Walk(tree, func(n *Node) bool {
+		n.Line, n.Pos = 0, 0
+		return true
+	})
+	treeJSON, _ := json.MarshalIndent(tree, "", "  ")
+	return string(treeJSON)
+}
+
+func TestToRunicRoundTrip(t *testing.T) {
+	for _, test := range parseTests {
+		testParser := New()
+		parsedTree := testParser.Parse(test.input)
+		runic := testParser.ToRunic(parsedTree)
+		reparsedTree := testParser.Parse(runic)
+
+		// compared as JSON so joined tags and loose lists are checked too
+		parsedTreeJSON := positionlessJSON(parsedTree)
+		reparsedTreeJSON := positionlessJSON(reparsedTree)
+		if parsedTreeJSON != reparsedTreeJSON {
+			t.Errorf("%s ERROR\nrunic: %q\nexpected: %s\nreceived: %s", test.name, runic, parsedTreeJSON, reparsedTreeJSON)
+			continue
+		}
+		if formatted := testParser.ToRunic(reparsedTree); formatted != runic {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, runic, formatted)
+		}
+	}
+}
+
+func TestToRunicWithOptions(t *testing.T) {
+	for _, test := range parseOptionTests {
+		testParser := New(test.opts...)
+		parsedTree := testParser.Parse(test.input)
+		runic := testParser.ToRunic(parsedTree)
+
+		parsedTreeJSON := positionlessJSON(parsedTree)
+		reparsedTreeJSON := positionlessJSON(testParser.Parse(runic))
+		if parsedTreeJSON != reparsedTreeJSON {
+			t.Errorf("%s ERROR\nrunic: %q\nexpected: %s\nreceived: %s", test.name, runic, parsedTreeJSON, reparsedTreeJSON)
+		}
+	}
+}
+
+type indentUnitTest struct {
+	name          string
+	unit          string
+	expectedRunic string
+}
+
+var indentUnitTests = []indentUnitTest{
+	{"default", "", "- One\n  - Two\n    1. Three\n- Four"},
+	{"tab", "\t", "- One\n\t- Two\n\t\t1. Three\n- Four"},
+	{"four spaces", "    ", "- One\n    - Two\n        1. Three\n- Four"},
+	{"single space rejected", " ", "- One\n  - Two\n    1. Three\n- Four"},
+	{"non-space rejected", "--", "- One\n  - Two\n    1. Three\n- Four"},
+}
+
+func TestToRunicIndentUnit(t *testing.T) {
+	input := "- One\n  - Two\n    1. Three\n- Four"
+	for _, test := range indentUnitTests {
+		opts := []Option{}
+		if test.unit != "" {
+			opts = append(opts, WithIndentUnit(test.unit))
+		}
+		testParser := New(opts...)
+		parsedTree := testParser.Parse(input)
+		runic := testParser.ToRunic(parsedTree)
+		if runic != test.expectedRunic {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedRunic, runic)
+			continue
+		}
+		if positionlessJSON(testParser.Parse(runic)) != positionlessJSON(parsedTree) {
+			t.Errorf("%s ERROR\nrunic %q doesn't parse to the same tree", test.name, runic)
+		}
+	}
+}
+
+type canonicalTest struct {
+	name              string
+	input             string
+	expectedCanonical string
+}
+
+var canonicalTests = []canonicalTest{
+	{
+		"whitespace and blank lines",
+		"  The quick   brown\nfox\n\n\n\n. Jumps   over",
+		"The quick brown fox\n\n. Jumps over",
+	},
+	{
+		"list indentation",
+		"- One\n    - Two\n - Three",
+		"- One\n  - Two\n- Three",
+	},
+	{
+		"comment kept",
+		"// a note\n\n\\// not a comment",
+		"// a note\n\n\\// not a comment",
+	},
+	{
+		"lists split by comment",
+		"- Item one\n// a note\n- Item two",
+		"- Item one\n\n// a note\n\n- Item two",
+	},
+	{
+		"comments between paragraphs",
+		"The quick fox\n\n// one\n\n// two   \njumps",
+		"The quick fox\n\n// one\n// two\n\njumps",
+	},
+	{
+		"frontmatter kept",
+		"---\ntitle: x\n---\n\n// note\nHello",
+		"---\ntitle: x\n---\n\n// note\n\nHello",
+	},
+}
+
+func TestCanonical(t *testing.T) {
+	for _, test := range canonicalTests {
+		testParser := New()
+		canonical := testParser.Canonical(test.input)
+		if canonical != test.expectedCanonical {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedCanonical, canonical)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+// malformedInputs are inputs which don't parse as written, and are only
+// written back the same once their errors are
+var malformedInputs = []string{
+	"Code[[",
+	"code[[a\\",
+	"(if[---- \\[x] varbold",
+	"bold[code[x",
+	"if[\r00]",
+	"vAr[[",
+	".\n\\ \\ ",
+	"if[A[}0",
+	"\\\\\n\\ \\ ",
+	"if[a\\[b]{x}",
+	"if[if[0{",
+	"0 \\ \\\\\n0",
+	"if[]{a} if[b",
+	"link(ftp://x)[a] image[b] color(nope)[c] var[]",
+}
+
+func TestCanonicalIdempotence(t *testing.T) {
+	inputs := []string{}
+	for _, test := range parseTests {
+		inputs = append(inputs, test.input)
+	}
+	for _, test := range canonicalTests {
+		inputs = append(inputs, test.input)
+	}
+	inputs = append(inputs, malformedInputs...)
+	for _, input := range inputs {
+		testParser := New()
+		canonical := testParser.Canonical(input)
+		if again := testParser.Canonical(canonical); again != canonical {
+			t.Errorf("%q ERROR\nexpected: %q\nreceived: %q", input, canonical, again)
+		}
+	}
+}
+
+func FuzzCanonical(f *testing.F) {
+	for _, test := range canonicalTests {
+		f.Add(test.input)
+	}
+	for _, input := range malformedInputs {
+		f.Add(input)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		testParser := New()
+		canonical := testParser.Canonical(input)
+		if again := testParser.Canonical(canonical); again != canonical {
+			t.Errorf("%q ERROR\nexpected: %q\nreceived: %q", input, canonical, again)
+		}
+	})
+}
@@ -1,9 +1,15 @@
 package runic
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
+	"html"
+	"io"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 type htmlCtxType int
@@ -11,97 +17,465 @@ type htmlCtxType int
 const (
 	htmlCtxNone htmlCtxType = iota
 	htmlCtxParagraph
+	htmlCtxItemParagraph // in the paragraph holding the content of an item in a loose list
 )
 
 func (p *parser) Html(input string) string {
-	tree := p.Parse(input)
-	s := ""
-	return p.toHtml(tree, &s, htmlCtxNone)
+	return renderHTML(p.Parse(input), p.config)
+}
+
+// NodeHtml renders a single node and its descendants with the parser's options,
+// e.g. one list from a tree returned by `Parse`
+func (p *parser) NodeHtml(n *Node) string {
+	// list items read whether their list is loose from the node being rendered,
+	// so the wrapper stands in for the parent
+	wrapper := &Node{Typ: nodeRoot, Children: []*Node{n}}
+	if n.parent != nil {
+		wrapper.Loose = n.parent.Loose
+	}
+	return renderHTML(wrapper, p.config)
+}
+
+// ErrOutputTooLarge is returned by `WriteHtml` when the output was cut short
+// by `WithMaxOutputBytes`
+var ErrOutputTooLarge = errors.New("runic: output exceeds the maximum size")
+
+// WriteHtml parses the input and writes it to `w` as HTML, the same as `Html`
+// would return, without building the whole document in memory. it returns the
+// number of bytes written and the first error returned by `w`, after which
+// nothing more is written
+func (p *parser) WriteHtml(w io.Writer, input string) (int, error) {
+	return writeHTML(w, p.Parse(input), p.config)
+}
+
+// renderHTML renders the given tree to HTML, depending on nothing beyond its
+// arguments
+func renderHTML(tree *Node, cfg config) string {
+	var s strings.Builder
+	writeHTML(&s, tree, cfg)
+	return s.String()
+}
+
+// writeHTML writes the given tree to `w` as HTML, see `renderHTML`
+func writeHTML(w io.Writer, tree *Node, cfg config) (int, error) {
+	hw := &htmlWriter{w: w, limit: cfg.maxOutputBytes, headingIDs: map[string]bool{}}
+	toHtml(tree, hw, htmlCtxNone, cfg)
+	hw.flush(true)
+	return hw.n, hw.err
+}
+
+// htmlWriter writes rendered HTML to an `io.Writer`. whitespace at the end of
+// the output is held back until something follows it, so a space separating
+// two nodes is written along with the second, and never left at the end of
+// output cut short by `WithMaxOutputBytes`
+type htmlWriter struct {
+	w     io.Writer
+	buf   []byte // output not written to `w` yet
+	n     int    // number of bytes written to `w`
+	limit int    // number of bytes which may be written to `w`, 0 for no limit
+	err   error  // first error returned by `w`, or `ErrOutputTooLarge`
+
+	headingIDs map[string]bool // ids given to headings so far, see `WithHeadingIDs`
+}
+
+func (w *htmlWriter) WriteString(s string) {
+	w.buf = append(w.buf, s...)
+	w.flush(false)
 }
 
-func (p *parser) toHtml(currentNode *Node, htmlString *string, htmlCtx htmlCtxType) string {
+// flush writes the held back output, except for any whitespace at its end
+// unless this is the final flush
+func (w *htmlWriter) flush(final bool) {
+	if w.err != nil {
+		return
+	}
+	end := len(w.buf)
+	if !final {
+		end = len(bytes.TrimRightFunc(w.buf, unicode.IsSpace))
+	}
+	if end == 0 {
+		return
+	}
+	if w.limit > 0 && w.n+end > w.limit {
+		w.err = ErrOutputTooLarge
+		return
+	}
+	n, err := w.w.Write(w.buf[:end])
+	w.n += n
+	w.err = err
+	w.buf = w.buf[:copy(w.buf, w.buf[end:])]
+}
+
+// isHidden reports whether a node renders as nothing, being a condition whose
+// flag isn't set, an unknown variable, or a paragraph holding only those
+func isHidden(n *Node, cfg config) bool {
+	switch n.Typ {
+	case nodeConditionTag:
+		return !cfg.flags[n.Val]
+	case nodeVariableTag:
+		_, ok := cfg.variables[n.Val]
+		return !ok
+	case nodeParagraph:
+		return len(n.Children) > 0 && !slices.ContainsFunc(n.Children, func(child *Node) bool {
+			return !isHidden(child, cfg)
+		})
+	}
+	return false
+}
+
+// toHtml writes the children of the given node. inline nodes are separated by
+// a space unless a node is joined to the one before it, so `bold[word]!` is
+// written as `<b>word</b>!`. a line break is never separated from either side,
+// and nor are the lists nested in a list item
+func toHtml(currentNode *Node, w *htmlWriter, htmlCtx htmlCtxType, cfg config) {
+	inline := !isOneOf(currentNode.Typ, nodeRoot, nodeList, nodeOrderedList, nodeBlockquote)
+	separated := false
 	for _, child := range currentNode.Children {
+		if w.err != nil {
+			return
+		}
+		// a node rendering as nothing needs no space either
+		if isHidden(child, cfg) {
+			continue
+		}
+		// an empty list has nothing to show, so it's dropped rather than
+		// rendered as `<ul></ul>`
+		if isOneOf(child.Typ, nodeList, nodeOrderedList) && len(child.Children) == 0 {
+			continue
+		}
+
+		if inline && !isOneOf(child.Typ, nodeList, nodeOrderedList) {
+			if separated && !child.Joined && child.Typ != nodeLineBreak {
+				w.WriteString(" ")
+			}
+			separated = child.Typ != nodeLineBreak
+		}
+
+		// the paragraph holding a loose item's content ends before its lists
+		if isOneOf(child.Typ, nodeList, nodeOrderedList) && htmlCtx == htmlCtxItemParagraph {
+			w.WriteString("</p>")
+			htmlCtx = htmlCtxNone
+		}
+
 		switch child.Typ {
 		case nodeError:
-			*htmlString += "<span class='error'>"
+			w.WriteString("<span class='error'>")
 		case nodeHeadingOne:
-			*htmlString += "<h1>"
+			w.WriteString("<h1" + htmlAttributes(child, w, cfg) + ">")
 		case nodeHeadingTwo:
-			*htmlString += "<h2>"
+			w.WriteString("<h2" + htmlAttributes(child, w, cfg) + ">")
 		case nodeHeadingThree:
-			*htmlString += "<h3>"
+			w.WriteString("<h3" + htmlAttributes(child, w, cfg) + ">")
 		case nodeHeadingFour:
-			*htmlString += "<h4>"
+			w.WriteString("<h4" + htmlAttributes(child, w, cfg) + ">")
 		case nodeHeadingFive:
-			*htmlString += "<h5>"
+			w.WriteString("<h5" + htmlAttributes(child, w, cfg) + ">")
 		case nodeHeadingSix:
-			*htmlString += "<h6>"
+			w.WriteString("<h6" + htmlAttributes(child, w, cfg) + ">")
 		case nodeParagraph:
-			*htmlString += "<p>"
+			w.WriteString("<p>")
 			htmlCtx = htmlCtxParagraph
 		case nodeBoldTag:
-			*htmlString += "<b>"
+			w.WriteString("<" + boldElement(cfg) + ">")
 		case nodeItalicTag:
-			*htmlString += "<em>"
+			w.WriteString("<em>")
+		case nodeUnderlineTag:
+			w.WriteString("<u>")
+		case nodeKbdTag:
+			w.WriteString("<kbd>")
+		case nodeMarkTag:
+			w.WriteString("<mark>")
+		case nodeCustomTag:
+			w.WriteString(cfg.customTags[child.Val].htmlOpen)
+		case nodeLinkTag:
+			w.WriteString(`<a href="` + html.EscapeString(child.Val) + `"` + htmlAttributes(child, w, cfg) + ">")
+		case nodeImageTag:
+			// the alt text is plain, so any tags inside it are left out
+			w.WriteString(`<img src="` + html.EscapeString(child.Val) + `" alt="` + html.EscapeString(child.Text()) + `">`)
+			continue
+		case nodeColorTag:
+			if class, ok := cfg.colorClasses[child.Val]; ok {
+				w.WriteString(`<span class="` + html.EscapeString(class) + `">`)
+			}
+		case nodeStrikeTag:
+			w.WriteString("<s>")
 		case nodeList:
-			*htmlString += "<ul>"
+			w.WriteString("<ul" + htmlAttributes(child, w, cfg) + ">")
+		case nodeOrderedList:
+			w.WriteString("<ol" + htmlAttributes(child, w, cfg) + ">")
+		case nodeBlockquote:
+			w.WriteString("<blockquote>")
+		case nodeRule:
+			w.WriteString("<hr>")
 		case nodeListItem:
-			*htmlString += "<li>"
+			w.WriteString("<li" + htmlAttributes(child, w, cfg) + ">")
+			if cfg.looseLists && currentNode.Loose {
+				w.WriteString("<p>")
+				htmlCtx = htmlCtxItemParagraph
+			}
+			if child.Checked {
+				w.WriteString(`<input type="checkbox" checked disabled>`)
+			} else if child.Task {
+				w.WriteString(`<input type="checkbox" disabled>`)
+			}
+			// separates the checkbox from the item's text
+			if child.Task && len(child.Children) > 0 {
+				w.WriteString(" ")
+			}
 		}
 
 		if child.Typ == nodeText {
-			*htmlString += child.Val + " "
+			text := html.EscapeString(child.Val)
+			if cfg.preserveSpaces {
+				text = preserveSpaces(text)
+			}
+			w.WriteString(text)
+		}
+
+		if child.Typ == nodeLineBreak {
+			w.WriteString("<br>")
+		}
+
+		if child.Typ == nodeCodeTag {
+			w.WriteString("<code>" + html.EscapeString(child.Val) + "</code>")
+		}
+
+		if child.Typ == nodeVariableTag {
+			w.WriteString(html.EscapeString(cfg.variables[child.Val]))
 		}
 
 		if len(child.Children) > 0 {
-			p.toHtml(child, htmlString, htmlCtx)
-			*htmlString = strings.TrimSpace(*htmlString)
+			toHtml(child, w, htmlCtx, cfg)
 		}
 
 		switch child.Typ {
 		case nodeError:
-			*htmlString += "</span>"
+			w.WriteString("</span>")
 		case nodeHeadingOne:
-			*htmlString += "</h1>"
+			w.WriteString("</h1>")
 		case nodeHeadingTwo:
-			*htmlString += "</h2>"
+			w.WriteString("</h2>")
 		case nodeHeadingThree:
-			*htmlString += "</h3>"
+			w.WriteString("</h3>")
 		case nodeHeadingFour:
-			*htmlString += "</h4>"
+			w.WriteString("</h4>")
 		case nodeHeadingFive:
-			*htmlString += "</h5>"
+			w.WriteString("</h5>")
 		case nodeHeadingSix:
-			*htmlString += "</h6>"
+			w.WriteString("</h6>")
 		case nodeParagraph:
-			*htmlString += "</p>"
+			w.WriteString("</p>")
 			htmlCtx = htmlCtxNone
 		case nodeBoldTag:
-			*htmlString += "</b> "
+			w.WriteString("</" + boldElement(cfg) + ">")
 		case nodeItalicTag:
-			*htmlString += "</em> "
+			w.WriteString("</em>")
+		case nodeUnderlineTag:
+			w.WriteString("</u>")
+		case nodeKbdTag:
+			w.WriteString("</kbd>")
+		case nodeMarkTag:
+			w.WriteString("</mark>")
+		case nodeCustomTag:
+			w.WriteString(cfg.customTags[child.Val].htmlClose)
+		case nodeLinkTag:
+			w.WriteString("</a>")
+		case nodeColorTag:
+			if _, ok := cfg.colorClasses[child.Val]; ok {
+				w.WriteString("</span>")
+			}
+		case nodeStrikeTag:
+			w.WriteString("</s>")
 		case nodeList:
-			*htmlString += "</ul>"
+			w.WriteString("</ul>")
+		case nodeOrderedList:
+			w.WriteString("</ol>")
+		case nodeBlockquote:
+			w.WriteString("</blockquote>")
 		case nodeListItem:
-			*htmlString += "</li>"
+			if _, nested := splitNestedLists(child); cfg.looseLists && currentNode.Loose && len(nested) == 0 {
+				w.WriteString("</p>")
+			}
+			w.WriteString("</li>")
+			htmlCtx = htmlCtxNone
 		}
 	}
+}
 
-	return *htmlString
+// preserveSpaces replaces each run of two or more spaces with `&nbsp;`, which
+// the browser doesn't collapse, see `WithPreserveSpaces`
+func preserveSpaces(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			b.WriteByte(s[i])
+			continue
+		}
+		end := i
+		for end < len(s) && s[end] == ' ' {
+			end++
+		}
+		if end-i == 1 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(strings.Repeat("&nbsp;", end-i))
+		}
+		i = end - 1
+	}
+	return b.String()
 }
 
-func (p *parser) htmlSanitiseSlice(start, end int) (s string) {
-	re := regexp.MustCompile("^\\s|\\s\\s+|\\s$")
-	s = strings.ReplaceAll(p.input[start:end], "\n", "<br>")
-	s = re.ReplaceAllStringFunc(s, func(s string) string {
-		return strings.Repeat("&nbsp;", len(s))
-	})
+// boldElement returns the element bold text is rendered in, see
+// `WithSemanticTags`
+func boldElement(cfg config) string {
+	if cfg.semanticTags {
+		return "strong"
+	}
+	return "b"
+}
+
+// htmlAttributes returns the attributes to render on the opening tag of the
+// given node, each preceded by a space. heading ids are kept unique across
+// everything rendered to `w`, and all share the prefix from
+// `WithHeadingIDPrefix`
+func htmlAttributes(n *Node, w *htmlWriter, cfg config) (attrs string) {
+	switch n.Typ {
+	case nodeHeadingOne, nodeHeadingTwo, nodeHeadingThree, nodeHeadingFour, nodeHeadingFive, nodeHeadingSix:
+		if cfg.headingIDs {
+			text := n.Text()
+			if cfg.maxHeadingText > 0 {
+				text = truncateWords(text, cfg.maxHeadingText)
+			}
+			attrs += ` id="` + html.EscapeString(cfg.headingIDPrefix+uniqueID(text, w.headingIDs)) + `"`
+		}
+	case nodeList, nodeOrderedList:
+		if cfg.microdata {
+			attrs += ` itemscope itemtype="https://schema.org/ItemList"`
+		}
+		if cfg.listDepth {
+			attrs += fmt.Sprintf(` data-depth="%d"`, n.Depth)
+		}
+		if cfg.listContinuation && n.Start > 0 {
+			attrs += fmt.Sprintf(` start="%d"`, n.Start)
+		}
+	case nodeLinkTag:
+		if cfg.linkTarget && isExternalURL(n.Val) {
+			attrs += ` target="_blank" rel="noopener noreferrer"`
+		}
+	case nodeListItem:
+		if cfg.microdata {
+			attrs += ` itemprop="itemListElement"`
+		}
+		if cfg.listNumbers && n.Number > 0 {
+			attrs += fmt.Sprintf(` value="%d"`, n.Number)
+		}
+	}
 	return
 }
 
-func (p *parser) HighlightText(input string) (highlightedText string) {
-	p.input = input
-	lexer := lex(input)
+// isExternalURL reports whether the url leads to another site, having an http
+// or https scheme or beginning with `//`. relative urls stay on the same site
+func isExternalURL(url string) bool {
+	scheme := urlScheme(url)
+	return scheme == "http" || scheme == "https" || scheme == "" && strings.HasPrefix(url, "//")
+}
+
+// significantSpace marks the whitespace in the input that `HighlightText`
+// always replaces with `&nbsp;`: tabs, and runs of two or more or at the start
+// or end of the input. runs are measured across the whole input rather than per
+// token, so a run straddling a token boundary renders the same as any other
+func significantSpace(input string) []bool {
+	significant := make([]bool, len(input))
+	for i := 0; i < len(input); {
+		if !isSanitisedSpace(input[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(input) && isSanitisedSpace(input[end]) {
+			end++
+		}
+		single := end-i == 1 && input[i] == ' ' && i > 0 && end < len(input)
+		for j := i; j < end; j++ {
+			significant[j] = !single
+		}
+		i = end
+	}
+	return significant
+}
+
+// isSanitisedSpace reports whether the byte is whitespace other than a line
+// ending, which is rendered as `<br>` instead
+func isSanitisedSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\f'
+}
+
+// htmlSanitiseSlice renders a slice of the input for `HighlightText`, with
+// line endings replaced by `<br>`, significant whitespace replaced by `&nbsp;`, and
+// characters with a meaning in HTML escaped. whitespace at either end of the
+// slice is also replaced, so it isn't lost between spans. each tab counts as
+// `tabSize` spaces
+func htmlSanitiseSlice(input string, start, end int, significant []bool, tabSize int) string {
+	var s strings.Builder
+	for i := start; i < end; i++ {
+		switch {
+		case input[i] == charReturn && strings.HasPrefix(input[i+1:], string(charNewline)):
+			// the `\n` of the pair writes the break
+		case input[i] == charNewline, input[i] == charReturn:
+			s.WriteString("<br>")
+		case input[i] == '\t':
+			s.WriteString(strings.Repeat("&nbsp;", tabSize))
+		case significant[i] || (isSanitisedSpace(input[i]) && (i == start || i == end-1)):
+			s.WriteString("&nbsp;")
+		case strings.IndexByte(`&<>"'`, input[i]) >= 0:
+			s.WriteString(html.EscapeString(input[i : i+1]))
+		default:
+			s.WriteByte(input[i])
+		}
+	}
+	return s.String()
+}
+
+func (p *parser) HighlightText(input string) string {
+	return highlightText(input, p.config)
+}
+
+// highlightClasses maps each token type to the class of its span in
+// `HighlightText`, following the prefix set by `WithClassPrefix`. tokens without
+// a class are written without a span
+var highlightClasses = map[tokenType]string{
+	typeText:          "text",
+	typeHeading:       "heading",
+	typeTag:           "tag",
+	typeOpeningSquare: "osq",
+	typeClosingSquare: "csq",
+	typeStrike:        "strike",
+	typeOpeningCurly:  "ocb",
+	typeClosingCurly:  "ccb",
+	typeBulletpoint:   "bulletpoint",
+	typeNumberpoint:   "numberpoint",
+	typeTagArg:        "arg",
+	typeQuote:         "quote",
+	typeLineBreak:     "linebreak",
+	typeCheckbox:      "checkbox",
+	typeComment:       "comment",
+	typeFrontmatter:   "frontmatter",
+	typeRule:          "rule",
+}
+
+// highlightSpan is a slice of the input covered by one token, from the byte
+// offset `Start` up to `End`
+type highlightSpan struct {
+	Class string `json:"class"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// highlightSpans splits the input into one span per token, together covering
+// all of the input
+func highlightSpans(input string, cfg config) (spans []highlightSpan) {
+	lexer := lex(input, cfg)
 
 	var prevToken token
 
@@ -114,23 +488,16 @@ func (p *parser) HighlightText(input string) (highlightedText string) {
 
 		start := prevToken.Pos
 		end := lexer.token.Pos
-
-		switch prevToken.Typ {
-		case typeText:
-			highlightedText += fmt.Sprintf(`<span class="runic__text">%s</span>`, p.htmlSanitiseSlice(start, end))
-		case typeHeading:
-			highlightedText += fmt.Sprintf(`<span class="runic__heading">%s</span>`, p.htmlSanitiseSlice(start, end))
-		case typeTag:
-			highlightedText += fmt.Sprintf(`<span class="runic__tag">%s</span>`, p.htmlSanitiseSlice(start, end))
-		case typeOpeningSquare:
-			highlightedText += fmt.Sprintf(`<span class="runic__osq">%s</span>`, p.htmlSanitiseSlice(start, end))
-		case typeClosingSquare:
-			highlightedText += fmt.Sprintf(`<span class="runic__csq">%s</span>`, p.htmlSanitiseSlice(start, end))
-		case typeBulletpoint:
-			highlightedText += fmt.Sprintf(`<span class="runic__bulletpoint">%s</span>`, p.htmlSanitiseSlice(start, end))
-		default:
-			highlightedText += p.htmlSanitiseSlice(start, end)
+		class := highlightClasses[prevToken.Typ]
+		if class != "" {
+			class = cfg.classPrefix + class
 		}
+		spans = append(spans, highlightSpan{
+			Class: class,
+			Text:  input[start:end],
+			Start: start,
+			End:   end,
+		})
 
 		prevToken = lexer.token
 	}
@@ -138,14 +505,65 @@ func (p *parser) HighlightText(input string) (highlightedText string) {
 	return
 }
 
-type editorData struct {
+// highlightText renders the input source with each token wrapped in a span,
+// depending on nothing beyond its arguments
+func highlightText(input string, cfg config) (highlightedText string) {
+	significant := significantSpace(input)
+
+	for _, span := range highlightSpans(input, cfg) {
+		text := htmlSanitiseSlice(input, span.Start, span.End, significant, cfg.tabSize)
+		if span.Class == "" {
+			highlightedText += text
+			continue
+		}
+		highlightedText += fmt.Sprintf(`<span class="%s">%s</span>`, html.EscapeString(span.Class), text)
+	}
+
+	return
+}
+
+// HighlightJSON returns the spans `HighlightText` is built from as JSON, each
+// with its class, raw text, and byte offsets in the input, so an editor can
+// build its own DOM. tokens without a class have an empty one
+func (p *parser) HighlightJSON(input string) ([]byte, error) {
+	spans := highlightSpans(input, p.config)
+	if spans == nil {
+		spans = []highlightSpan{}
+	}
+	return json.Marshal(spans)
+}
+
+// EditorData holds what an editor shows for a document: its HTML, its
+// highlighted source, and the tree the HTML is rendered from
+type EditorData struct {
 	Html          string `json:"html"`
 	HighlightText string `json:"highlightText"`
+	Tree          *Node  `json:"tree"`
 }
 
-func (p *parser) EditorData(input string) (*editorData, error) {
-	return &editorData{
-		Html:          p.Html(input),
+// EditorData parses the input once for all of `EditorData`. the error is
+// `ErrOutputTooLarge` when the HTML was cut short by `WithMaxOutputBytes`, and
+// the data is returned either way
+func (p *parser) EditorData(input string) (*EditorData, error) {
+	tree := p.Parse(input)
+	var s strings.Builder
+	_, err := writeHTML(&s, tree, p.config)
+	return &EditorData{
+		Html:          s.String(),
 		HighlightText: p.HighlightText(input),
-	}, nil
+		Tree:          tree,
+	}, err
+}
+
+// ParseEditorData reads back an `EditorData` from its JSON, with each node of
+// the tree linked to its parent again
+func ParseEditorData(data []byte) (*EditorData, error) {
+	editorData := &EditorData{}
+	if err := json.Unmarshal(data, editorData); err != nil {
+		return nil, err
+	}
+	if editorData.Tree != nil {
+		linkParents(editorData.Tree)
+	}
+	return editorData, nil
 }
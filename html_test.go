@@ -1,6 +1,14 @@
 package runic
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
 
 type htmlTest struct {
 	name         string
@@ -59,6 +67,161 @@ var htmlTests = []htmlTest{
 		"The quick bold[brown fox italic[jumps] over the] lazy dog",
 		"<p>The quick <b>brown fox <em>jumps</em> over the</b> lazy dog</p>",
 	},
+	{
+		"nested same-type tags",
+		"The bold[quick bold[brown] fox] jumps",
+		"<p>The <b>quick <b>brown</b> fox</b> jumps</p>",
+	},
+	{
+		"strike",
+		"The strike[removed] fox",
+		"<p>The <s>removed</s> fox</p>",
+	},
+	{
+		"strike nested in bold",
+		"The bold[quick strike[gone]] fox",
+		"<p>The <b>quick <s>gone</s></b> fox</p>",
+	},
+	{
+		"strike without closing square",
+		"strike[The quick",
+		"<p><s>The quick</s></p>",
+	},
+	{
+		"code",
+		"Call code[fmt.Println] to print",
+		"<p>Call <code>fmt.Println</code> to print</p>",
+	},
+	{
+		"code w/ tags kept as text",
+		"The code[bold[x] italic[y]] fox",
+		"<p>The <code>bold[x] italic[y]</code> fox</p>",
+	},
+	{
+		"code w/ escaped text",
+		`code[if a < b && c > "d" {]`,
+		"<p><code>if a &lt; b &amp;&amp; c &gt; &#34;d&#34; {</code></p>",
+	},
+	{
+		"code w/o closing square",
+		"bold[The code[quick\n\nbrown fox",
+		"<p><b>The <code>quick</code></b></p><p>brown fox</p>",
+	},
+	{
+		"link",
+		"See link(https://example.com/?a=1&b=2)[click here] now",
+		`<p>See <a href="https://example.com/?a=1&amp;b=2">click here</a> now</p>`,
+	},
+	{
+		"link w/ quote in url",
+		`link(https://example.com/"><script>)[click]`,
+		`<p><a href="https://example.com/&#34;&gt;&lt;script&gt;">click</a></p>`,
+	},
+	{
+		"image",
+		`See image(https://x/y.png?a=1&b=2)[a "cat"] now`,
+		`<p>See <img src="https://x/y.png?a=1&amp;b=2" alt="a &#34;cat&#34;"> now</p>`,
+	},
+	{
+		"image w/ tags in alt text",
+		"image(y.png)[a bold[big] cat]",
+		`<p><img src="y.png" alt="a big cat"></p>`,
+	},
+	{
+		"image w/o alt text",
+		"image(y.png)[]",
+		`<p><img src="y.png" alt=""></p>`,
+	},
+	{
+		"image w/ quote in url",
+		`image(y.png"><script>)[cat]`,
+		`<p><img src="y.png&#34;&gt;&lt;script&gt;" alt="cat"></p>`,
+	},
+	{
+		"image w/ empty url",
+		"See image()[a cat] now",
+		"<p>See <span class='error'>a cat</span> now</p>",
+	},
+	{
+		"link w/ empty url",
+		"See link()[docs] now",
+		"<p>See <span class='error'>docs</span> now</p>",
+	},
+	{
+		"image inside link",
+		"See link(https://x)[image(https://y.png)[alt]] now",
+		`<p>See <a href="https://x"><img src="https://y.png" alt="alt"></a> now</p>`,
+	},
+	{
+		"link w/ javascript url",
+		"See link(javascript:alert`1`)[click] now",
+		"<p>See <span class='error'>click</span> now</p>",
+	},
+	{
+		"image w/ javascript url",
+		"See image(JavaScript:alert`1`)[a cat] now",
+		"<p>See <span class='error'>a cat</span> now</p>",
+	},
+	{
+		"links w/ allowed urls",
+		"link(/docs:intro)[a] link(mailto:fox@example.com)[b] link(HTTPS://example.com)[c]",
+		`<p><a href="/docs:intro">a</a> <a href="mailto:fox@example.com">b</a> <a href="HTTPS://example.com">c</a></p>`,
+	},
+	{
+		"rich text w/ escaped squares",
+		"The bold[a \\[ b \\] c] fox",
+		"<p>The <b>a [ b ] c</b> fox</p>",
+	},
+	{
+		"underline",
+		"The quick underline[brown fox] jumps",
+		"<p>The quick <u>brown fox</u> jumps</p>",
+	},
+	{
+		"kbd",
+		"Press kbd[Ctrl]+kbd[C] to copy",
+		"<p>Press <kbd>Ctrl</kbd>+<kbd>C</kbd> to copy</p>",
+	},
+	{
+		"tag followed by punctuation",
+		"The bold[word]! and italic[fox], code[x]. if[]{!}",
+		"<p>The <b>word</b>! and <em>fox</em>, <code>x</code>. <span class='error'>!</span></p>",
+	},
+	{
+		"tag followed by a word",
+		"bold[a] b italic[c]d",
+		"<p><b>a</b> b <em>c</em>d</p>",
+	},
+	{
+		"kbd w/ escaped content",
+		"Press kbd[<Enter> & \"Esc\"]",
+		"<p>Press <kbd>&lt;Enter&gt; &amp; &#34;Esc&#34;</kbd></p>",
+	},
+	{
+		"tags w/ mixed case names",
+		"Bold[The] ITALIC[quick] Link(https://example.com)[fox] Code[bold[x]]",
+		`<p><b>The</b> <em>quick</em> <a href="https://example.com">fox</a> <code>bold[x]</code></p>`,
+	},
+	{
+		"mark",
+		"The mark[important] fox",
+		"<p>The <mark>important</mark> fox</p>",
+	},
+	{
+		"mark nested in bold",
+		"The bold[mark[both] quick italic[mark[fox]]] jumps",
+		"<p>The <b><mark>both</mark> quick <em><mark>fox</mark></em></b> jumps</p>",
+	},
+	{
+		"nested rich text w/ underline inside",
+		"The quick bold[brown italic[fox underline[jumps]] over] the lazy dog",
+		"<p>The quick <b>brown <em>fox <u>jumps</u></em> over</b> the lazy dog</p>",
+	},
+	{
+		"nested rich text w/ underline outside",
+		"The quick underline[brown fox bold[jumps] over the] lazy dog",
+		"<p>The quick <u>brown fox <b>jumps</b> over the</u> lazy dog</p>",
+	},
 	{
 		"list",
 		"- Item one\n- Item two\n- Item three",
@@ -78,7 +241,7 @@ var htmlTests = []htmlTest{
 	       - Item one
            - Item two
 	   `,
-		"<ul><li>Item one</li><ul><li>Item two</li></ul></ul>",
+		"<ul><li>Item one</li><li>Item two</li></ul>",
 	},
 	{
 		"list with indents v3",
@@ -89,7 +252,207 @@ var htmlTests = []htmlTest{
           - Item four
         - Item five
     `,
-		"<ul><li>Item one</li><ul><li>Item two</li><ul><li>Item three</li></ul><li>Item four</li></ul><li>Item five</li></ul>",
+		"<ul><li>Item one<ul><li>Item two<ul><li>Item three</li></ul></li><li>Item four</li></ul></li><li>Item five</li></ul>",
+	},
+	{
+		"heading w/ only whitespace",
+		". ",
+		"<h1></h1>",
+	},
+	{
+		"heading w/ only newline",
+		":\n",
+		"<h2></h2>",
+	},
+	{
+		"rich text w/ no space before tag",
+		"The quick brown fox\\bold[jumps over] the lazy dog",
+		"<p>The quick brown fox<b>jumps over</b> the lazy dog</p>",
+	},
+	{
+		"nested rich text w/ no space before tags",
+		"The quick\\bold[brown fox\\italic[jumps]] over",
+		"<p>The quick<b>brown fox<em>jumps</em></b> over</p>",
+	},
+	{
+		"adjacent tags",
+		"bold[a]italic[b]",
+		"<p><b>a</b><em>b</em></p>",
+	},
+	{
+		"space separated tags",
+		"bold[a] italic[b]",
+		"<p><b>a</b> <em>b</em></p>",
+	},
+	{
+		"adjacent tags within text",
+		"The bold[quick]italic[brown]bold[fox] jumps",
+		"<p>The <b>quick</b><em>brown</em><b>fox</b> jumps</p>",
+	},
+	{
+		"escaped text in paragraph",
+		`The sum 5 < 6 & "quoted" <script>alert('x')</script>`,
+		"<p>The sum 5 &lt; 6 &amp; &#34;quoted&#34; &lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;</p>",
+	},
+	{
+		"escaped text in heading",
+		`. 5 < 6 & "quoted"`,
+		"<h1>5 &lt; 6 &amp; &#34;quoted&#34;</h1>",
+	},
+	{
+		"escaped text in list item and tag",
+		`- 5 < 6 & bold["quoted"]`,
+		"<ul><li>5 &lt; 6 &amp; <b>&#34;quoted&#34;</b></li></ul>",
+	},
+	{
+		"escaped entity",
+		"Fish &amp; chips",
+		"<p>Fish &amp;amp; chips</p>",
+	},
+	{
+		"ordered list",
+		"1. Item one\n2. Item two\n3. Item three",
+		"<ol><li>Item one</li><li>Item two</li><li>Item three</li></ol>",
+	},
+	{
+		"task items nested in list",
+		"- Item one\n  - [ ] Item two\n  - [x] Item three\n  - [ ]\n- Item four",
+		`<ul><li>Item one<ul><li><input type="checkbox" disabled> Item two</li><li><input type="checkbox" checked disabled> Item three</li><li><input type="checkbox" disabled></li></ul></li><li>Item four</li></ul>`,
+	},
+	{
+		"task items in ordered list",
+		"1. [X] Item one\n2. [x]Item two",
+		`<ol><li><input type="checkbox" checked disabled> Item one</li><li>[x]Item two</li></ol>`,
+	},
+	{
+		"mixed nested lists",
+		"1. Item one\n  - Item two\n    1. Item three\n  - Item four\n2. Item five",
+		"<ol><li>Item one<ul><li>Item two<ol><li>Item three</li></ol></li><li>Item four</li></ul></li><li>Item five</li></ol>",
+	},
+	{
+		"comment between paragraphs",
+		"Para one\n\n// a bold[note] for authors\n\nPara two",
+		"<p>Para one</p><p>Para two</p>",
+	},
+	{
+		"comment directly before paragraph",
+		"Para one\n\n// a note\nPara two // not a comment",
+		"<p>Para one</p><p>Para two // not a comment</p>",
+	},
+	{
+		"comment mid-paragraph",
+		"Para one\n// a note\nPara two",
+		"<p>Para one</p><p>Para two</p>",
+	},
+	{
+		"comment mid-list",
+		"- Item one\n  // a note\n- Item two",
+		"<ul><li>Item one</li></ul><ul><li>Item two</li></ul>",
+	},
+	{
+		"escaped comment marker",
+		`\// not a comment`,
+		"<p>// not a comment</p>",
+	},
+	{
+		"single line quote",
+		"> bold[Quoted] text",
+		"<blockquote><p><b>Quoted</b> text</p></blockquote>",
+	},
+	{
+		"multi line quote",
+		"> Line one\n> Line two\n>\n> Line three",
+		"<blockquote><p>Line one Line two</p><p>Line three</p></blockquote>",
+	},
+	{
+		"quote followed by paragraph",
+		"> Quote\nParagraph",
+		"<blockquote><p>Quote</p></blockquote><p>Paragraph</p>",
+	},
+	{
+		"quotes separated by blank line",
+		"> Quote one\n\n> Quote two",
+		"<blockquote><p>Quote one</p></blockquote><blockquote><p>Quote two</p></blockquote>",
+	},
+	{
+		"tag name split by escaped newline",
+		"The bo\\\nld[fox] jumps",
+		"<p>The bo<span class='error'>fox</span> jumps</p>",
+	},
+	{
+		"tag joined by escaped newline",
+		"The quick\\\nbold[fox] jumps",
+		"<p>The quick<b>fox</b> jumps</p>",
+	},
+	{
+		"line break",
+		"The quick \\\\\nbrown fox\njumps",
+		"<p>The quick<br>brown fox jumps</p>",
+	},
+	{
+		"line break in tag",
+		"The bold[quick\\\\\nbrown] fox",
+		"<p>The <b>quick<br>brown</b> fox</p>",
+	},
+	{
+		"bare closing square",
+		"a ] b",
+		"<p>a ] b</p>",
+	},
+	{
+		"bare squares",
+		"The [quick] brown]fox [ jumps",
+		"<p>The [quick] brown]fox [ jumps</p>",
+	},
+	{
+		"bare closing square after tag",
+		"bold[a] ] b",
+		"<p><b>a</b> ] b</p>",
+	},
+	{
+		"closing square of tag closed by end of quote line",
+		"> bold[Quoted\n> text] here",
+		"<blockquote><p><b>Quoted</b> text here</p></blockquote>",
+	},
+	{
+		"paragraph tag",
+		"The quick paragraph[brown fox] jumps\nover paragraph[the lazy dog]",
+		"<p>The quick</p><p>brown fox</p><p>jumps over</p><p>the lazy dog</p>",
+	},
+	{
+		"empty paragraph tag",
+		"The quick paragraph[] brown fox",
+		"<p>The quick</p><p>brown fox</p>",
+	},
+	{
+		"paragraph of empty tags",
+		"The quick\n\nbold[] italic[bold[]]\n\n> bold[]\n\nbrown fox",
+		"<p>The quick</p><blockquote></blockquote><p>brown fox</p>",
+	},
+	{
+		"rule between paragraphs",
+		"Roses\n---\nViolets",
+		"<p>Roses</p><hr><p>Violets</p>",
+	},
+	{
+		"rule of equals signs between blank lines",
+		"Roses\n\n=== \n\nViolets",
+		"<p>Roses</p><hr><p>Violets</p>",
+	},
+	{
+		"rule between lists",
+		"- a\n---\n- b",
+		"<ul><li>a</li></ul><hr><ul><li>b</li></ul>",
+	},
+	{
+		"two hyphens are a list item",
+		"-- ",
+		"<ul><li>-</li></ul>",
+	},
+	{
+		"hyphens inside text",
+		"Roses ---",
+		"<p>Roses ---</p>",
 	},
 }
 
@@ -193,6 +556,76 @@ var highlightTextTests = []highlightTextTest{
     `,
 		`<span class="runic__heading"><br>&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;.&nbsp;</span><span class="runic__text">This is a level one heading<br><br><br>&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;</span>&nbsp;<span class="runic__text">The quick brown fox jumps over the lazy dog<br><br>&nbsp;&nbsp;&nbsp;</span>&nbsp;`,
 	},
+	{
+		"whitespace run straddling a tag boundary",
+		"The quick bold[brown]\n\n   fox",
+		`<span class="runic__text">The quick&nbsp;</span><span class="runic__tag">bold</span><span class="runic__osq">[</span><span class="runic__text">brown</span><span class="runic__csq">]<br><br>&nbsp;&nbsp;</span>&nbsp;<span class="runic__text">fox</span>`,
+	},
+	{
+		"whitespace run straddling a paragraph boundary",
+		"bold[brown fox]   \n\n  jumps",
+		`<span class="runic__tag">bold</span><span class="runic__osq">[</span><span class="runic__text">brown fox</span><span class="runic__csq">]&nbsp;&nbsp;&nbsp;<br><br>&nbsp;</span>&nbsp;<span class="runic__text">jumps</span>`,
+	},
+	{
+		"escaped text",
+		`5 < 6 & bold["quoted"]`,
+		`<span class="runic__text">5 &lt; 6 &amp;&nbsp;</span><span class="runic__tag">bold</span><span class="runic__osq">[</span><span class="runic__text">&#34;quoted&#34;</span><span class="runic__csq">]</span>`,
+	},
+	{
+		"strike tag",
+		"strike[gone]",
+		`<span class="runic__tag">strike</span><span class="runic__osq">[</span><span class="runic__text">gone</span><span class="runic__csq">]</span>`,
+	},
+	{
+		"link",
+		"link(https://example.com)[docs]",
+		`<span class="runic__tag">link</span><span class="runic__arg">(https://example.com)</span><span class="runic__osq">[</span><span class="runic__text">docs</span><span class="runic__csq">]</span>`,
+	},
+	{
+		"ordered list",
+		"1. Item one",
+		`<span class="runic__numberpoint">1.&nbsp;</span><span class="runic__text">Item one</span>`,
+	},
+	{
+		"frontmatter",
+		"---\ntitle: Runic\n---\nText",
+		`<span class="runic__frontmatter">---<br>title: Runic<br>---<br></span><span class="runic__text">Text</span>`,
+	},
+	{
+		"comment",
+		"// a note\nText",
+		`<span class="runic__comment">// a note<br></span><span class="runic__text">Text</span>`,
+	},
+	{
+		"task item",
+		"- [x] Item one",
+		`<span class="runic__bulletpoint">-&nbsp;</span><span class="runic__checkbox">[x]&nbsp;</span><span class="runic__text">Item one</span>`,
+	},
+	{
+		"quote",
+		"> Quote",
+		`<span class="runic__quote">&gt;&nbsp;</span><span class="runic__text">Quote</span>`,
+	},
+	{
+		"mark",
+		"mark[important]",
+		`<span class="runic__tag">mark</span><span class="runic__osq">[</span><span class="runic__text">important</span><span class="runic__csq">]</span>`,
+	},
+	{
+		"line break",
+		"The quick\\\\\nfox",
+		`<span class="runic__text">The quick</span><span class="runic__linebreak">\\<br></span><span class="runic__text">fox</span>`,
+	},
+	{
+		"CRLF and CR newlines",
+		"The quick\r\nbrown\rfox",
+		`<span class="runic__text">The quick<br>brown<br>fox</span>`,
+	},
+	{
+		"rule",
+		"a\n---",
+		`<span class="runic__text">a</span><br><span class="runic__rule">---</span>`,
+	},
 }
 
 func TestHighlightText(t *testing.T) {
@@ -206,3 +639,758 @@ func TestHighlightText(t *testing.T) {
 		t.Log(test.name, "OK")
 	}
 }
+
+type htmlOptionTest struct {
+	name         string
+	input        string
+	opts         []Option
+	expectedHtml string
+}
+
+var htmlOptionTests = []htmlOptionTest{
+	{
+		"auto link mid-sentence",
+		"See https://example.com/?a=1&b=2 for bold[the docs]",
+		[]Option{WithAutoLinks()},
+		`<p>See <a href="https://example.com/?a=1&amp;b=2">https://example.com/?a=1&amp;b=2</a> for <b>the docs</b></p>`,
+	},
+	{
+		"auto link ending a sentence",
+		"See https://example.com.",
+		[]Option{WithAutoLinks()},
+		`<p>See <a href="https://example.com">https://example.com</a>.</p>`,
+	},
+	{
+		"auto link in tag",
+		"Then bold[see https://example.org/docs/] now",
+		[]Option{WithAutoLinks()},
+		`<p>Then <b>see <a href="https://example.org/docs/">https://example.org/docs/</a></b> now</p>`,
+	},
+	{
+		"auto link w/ parentheses",
+		"See https://en.wikipedia.org/wiki/Fox_(disambiguation) for more",
+		[]Option{WithAutoLinks()},
+		`<p>See <a href="https://en.wikipedia.org/wiki/Fox_(disambiguation)">https://en.wikipedia.org/wiki/Fox_(disambiguation)</a> for more</p>`,
+	},
+	{
+		"auto link inside link",
+		"link(https://example.com)[docs at https://example.com] link[https://example.org]",
+		[]Option{WithAutoLinks()},
+		`<p><a href="https://example.com">docs at https://example.com</a> <span class='error'>https://example.org</span></p>`,
+	},
+	{
+		"auto link w/o host or word boundary",
+		"https:// and xhttps://example.com",
+		[]Option{WithAutoLinks()},
+		"<p>https:// and xhttps://example.com</p>",
+	},
+	{
+		"bare url w/o auto links",
+		"See https://example.com.",
+		nil,
+		"<p>See https://example.com.</p>",
+	},
+	{
+		"five-space gap w/ preserved spaces",
+		"The quick     brown fox",
+		[]Option{WithPreserveSpaces()},
+		"<p>The quick&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;brown fox</p>",
+	},
+	{
+		"preserved spaces after tag",
+		"bold[The quick]     brown fox",
+		[]Option{WithPreserveSpaces()},
+		"<p><b>The quick</b>&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;brown fox</p>",
+	},
+	{
+		"preserved spaces w/ max paragraph length",
+		"aaaaaaaaaaaa  bbbbbb cccc abc  dddddddddddd",
+		[]Option{WithPreserveSpaces(), WithMaxParagraphLength(10)},
+		"<p>aaaaaaaaaaaa</p><p>bbbbbb</p><p>cccc abc</p><p>dddddddddddd</p>",
+	},
+	{
+		"preserved spaces cut w/ max paragraph length",
+		"aaaaaaaaaaaa  b c",
+		[]Option{WithPreserveSpaces(), WithMaxParagraphLength(10)},
+		"<p>aaaaaaaaaaaa</p><p>b c</p>",
+	},
+	{
+		"five-space gap",
+		"The quick     brown fox",
+		nil,
+		"<p>The quick brown fox</p>",
+	},
+	{
+		"custom tag",
+		"The spoiler[quick bold[brown] fox] jumps",
+		[]Option{WithCustomTag("spoiler", `<span class="spoiler">`, "</span>")},
+		`<p>The <span class="spoiler">quick <b>brown</b> fox</span> jumps</p>`,
+	},
+	{
+		"custom tags nested",
+		"spoiler[The abbr[quick]] fox",
+		[]Option{WithCustomTag("spoiler", `<span class="spoiler">`, "</span>"), WithCustomTag("abbr", "<abbr>", "</abbr>")},
+		`<p><span class="spoiler">The <abbr>quick</abbr></span> fox</p>`,
+	},
+	{
+		"custom tag can't redefine built-in tag",
+		"The bold[fox] tag[jumps]",
+		[]Option{WithCustomTag("bold", "<span>", "</span>")},
+		"<p>The <b>fox</b> <span class='error'>jumps</span></p>",
+	},
+	{
+		"preserve empty paragraphs",
+		"Paragraph one\n\n\n\nParagraph two\n\n\n",
+		[]Option{WithPreserveEmptyParagraphs()},
+		"<p>Paragraph one</p><p></p><p></p><p>Paragraph two</p>",
+	},
+	{
+		"paragraph break of 1",
+		"Line one\nLine two",
+		[]Option{WithParagraphBreak(1)},
+		"<p>Line one</p><p>Line two</p>",
+	},
+	{
+		"stanza",
+		"Roses are red\nViolets are blue\nSugar is sweet\n\nAnd so are you",
+		nil,
+		"<p>Roses are red Violets are blue Sugar is sweet</p><p>And so are you</p>",
+	},
+	{
+		"stanza w/ hard line breaks",
+		"Roses are red\nViolets are blue\nSugar is sweet\n\nAnd so are you",
+		[]Option{WithHardLineBreaks()},
+		"<p>Roses are red<br>Violets are blue<br>Sugar is sweet</p><p>And so are you</p>",
+	},
+	{
+		"stanza w/ hard line breaks and paragraph merging",
+		"Roses are red\nViolets are blue\n\nSugar is sweet\n",
+		[]Option{WithHardLineBreaks(), WithParagraphMerging()},
+		"<p>Roses are red<br>Violets are blue<br>Sugar is sweet</p>",
+	},
+	{
+		"escaped newline w/ hard line breaks",
+		"Roses are red\\\nViolets are blue",
+		[]Option{WithHardLineBreaks()},
+		"<p>Roses are redViolets are blue</p>",
+	},
+	{
+		"list and heading w/ hard line breaks",
+		". Roses\n- Violets\n- Sugar\nis sweet",
+		[]Option{WithHardLineBreaks()},
+		"<h1>Roses</h1><ul><li>Violets</li><li>Sugar</li></ul><p>is sweet</p>",
+	},
+	{
+		"list depth",
+		"- Item one\n  - Item two\n- Item three",
+		[]Option{WithListDepth()},
+		`<ul data-depth="0"><li>Item one<ul data-depth="1"><li>Item two</li></ul></li><li>Item three</li></ul>`,
+	},
+	{
+		"tight list w/ loose detection",
+		"- Item one\n- Item two\n- Item three",
+		[]Option{WithListLooseDetection()},
+		"<ul><li>Item one</li><li>Item two</li><li>Item three</li></ul>",
+	},
+	{
+		"loose list w/ loose detection",
+		"- Item one\n\n- Item two\n- Item three",
+		[]Option{WithListLooseDetection()},
+		"<ul><li><p>Item one</p></li><li><p>Item two</p></li><li><p>Item three</p></li></ul>",
+	},
+	{
+		"loose list w/ tight nested list",
+		"- Item one\n  - Item two\n  - Item three\n\n- Item four",
+		[]Option{WithListLooseDetection()},
+		"<ul><li><p>Item one</p><ul><li>Item two</li><li>Item three</li></ul></li><li><p>Item four</p></li></ul>",
+	},
+	{
+		"list loosened by blank line before nested list",
+		"- Item one\n\n  - Item two\n  - Item three\n- Item four",
+		[]Option{WithListLooseDetection()},
+		"<ul><li><p>Item one</p><ul><li>Item two</li><li>Item three</li></ul></li><li><p>Item four</p></li></ul>",
+	},
+	{
+		"loose list w/o loose detection",
+		"- Item one\n\n- Item two\n- Item three",
+		nil,
+		"<ul><li>Item one</li><li>Item two</li><li>Item three</li></ul>",
+	},
+	{
+		"microdata",
+		": How to make tea\n- Boil the kettle\n- Add the bold[tea] bag",
+		[]Option{WithMicrodata()},
+		`<h2>How to make tea</h2><ul itemscope itemtype="https://schema.org/ItemList"><li itemprop="itemListElement">Boil the kettle</li><li itemprop="itemListElement">Add the <b>tea</b> bag</li></ul>`,
+	},
+	{
+		"semantic tags",
+		"The quick bold[brown fox italic[jumps] over the] lazy dog",
+		[]Option{WithSemanticTags()},
+		"<p>The quick <strong>brown fox <em>jumps</em> over the</strong> lazy dog</p>",
+	},
+	{
+		"semantic tags w/ no space before tags",
+		"The quick\\bold[brown fox\\italic[jumps]] over",
+		[]Option{WithSemanticTags()},
+		"<p>The quick<strong>brown fox<em>jumps</em></strong> over</p>",
+	},
+	{
+		"heading ids",
+		". This is a level one heading\n: bold[Second] heading, v2!\n:: Ünïcödé 日本語",
+		[]Option{WithHeadingIDs()},
+		`<h1 id="this-is-a-level-one-heading">This is a level one heading</h1><h2 id="second-heading-v2"><b>Second</b> heading, v2!</h2><h4 id="ünïcödé-日本語">Ünïcödé 日本語</h4>`,
+	},
+	{
+		"heading ids w/ duplicates",
+		": Notes\nThe quick fox\n\n: Notes\n: notes\n:",
+		[]Option{WithHeadingIDs()},
+		`<h2 id="notes">Notes</h2><p>The quick fox</p><h2 id="notes-1">Notes</h2><h2 id="notes-2">notes</h2><h2 id="heading"></h2>`,
+	},
+	{
+		"heading ids w/ microdata",
+		". Title",
+		[]Option{WithHeadingIDs(), WithMicrodata()},
+		`<h1 id="title">Title</h1>`,
+	},
+	{
+		"ordered list renumbered",
+		"1. a\n3. b\n7. c",
+		nil,
+		"<ol><li>a</li><li>b</li><li>c</li></ol>",
+	},
+	{
+		"ordered list numbers from source",
+		"1. a\n3. b\n7. c\n  2. d",
+		[]Option{WithListStartFromSource()},
+		`<ol><li value="1">a</li><li value="3">b</li><li value="7">c<ol><li value="2">d</li></ol></li></ol>`,
+	},
+	{
+		"ordered list restarted after interruption",
+		"1. a\n2. b\n\npara\n\n3. c",
+		nil,
+		"<ol><li>a</li><li>b</li></ol><p>para</p><ol><li>c</li></ol>",
+	},
+	{
+		"ordered list continuation",
+		"1. a\n2. b\n\npara\n\n3. c\n  1. d\n\n. Heading\n\n5. e\n\npara\n\n1. f",
+		[]Option{WithOrderedListContinuation()},
+		`<ol><li>a</li><li>b</li></ol><p>para</p><ol start="3"><li>c<ol><li>d</li></ol></li></ol><h1>Heading</h1><ol start="4"><li>e</li></ol><p>para</p><ol><li>f</li></ol>`,
+	},
+	{
+		"link target on external links",
+		"link(https://example.com)[docs] and link(//cdn.example.com/a)[cdn]",
+		[]Option{WithLinkTarget()},
+		`<p><a href="https://example.com" target="_blank" rel="noopener noreferrer">docs</a> and <a href="//cdn.example.com/a" target="_blank" rel="noopener noreferrer">cdn</a></p>`,
+	},
+	{
+		"link target left off relative links",
+		"link(/docs)[docs] link(#top)[top] link(mailto:fox@example.com)[mail]",
+		[]Option{WithLinkTarget()},
+		`<p><a href="/docs">docs</a> <a href="#top">top</a> <a href="mailto:fox@example.com">mail</a></p>`,
+	},
+	{
+		"link target w/ auto links",
+		"See https://example.com",
+		[]Option{WithLinkTarget(), WithAutoLinks()},
+		`<p>See <a href="https://example.com" target="_blank" rel="noopener noreferrer">https://example.com</a></p>`,
+	},
+	{
+		"heading ids w/ max heading text",
+		". The quick brown fox jumps over the lazy dog\n. The quick brown fox sleeps",
+		[]Option{WithHeadingIDs(), WithMaxHeadingText(20)},
+		`<h1 id="the-quick-brown-fox">The quick brown fox jumps over the lazy dog</h1><h1 id="the-quick-brown-fox-1">The quick brown fox sleeps</h1>`,
+	},
+	{
+		"heading ids w/ prefix",
+		": Notes\n: Notes",
+		[]Option{WithHeadingIDs(), WithHeadingIDPrefix("intro-")},
+		`<h2 id="intro-notes">Notes</h2><h2 id="intro-notes-1">Notes</h2>`,
+	},
+	{
+		"microdata w/ list depth",
+		"- Item one\n  - Item two",
+		[]Option{WithMicrodata(), WithListDepth()},
+		`<ul itemscope itemtype="https://schema.org/ItemList" data-depth="0"><li itemprop="itemListElement">Item one<ul itemscope itemtype="https://schema.org/ItemList" data-depth="1"><li itemprop="itemListElement">Item two</li></ul></li></ul>`,
+	},
+	{
+		"condition w/ flag set",
+		"The quick if[draft]{brown bold[fox]} jumps",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<p>The quick brown <b>fox</b> jumps</p>",
+	},
+	{
+		"condition w/ flag unset",
+		"The quick if[draft]{brown bold[fox]} jumps",
+		[]Option{WithFlags(map[string]bool{"draft": false})},
+		"<p>The quick jumps</p>",
+	},
+	{
+		"condition w/ unknown flag",
+		"The quick if[published]{brown fox} jumps",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<p>The quick jumps</p>",
+	},
+	{
+		"nested conditions",
+		"if[draft]{The if[internal]{quick} brown} fox",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<p>The brown fox</p>",
+	},
+	{
+		"condition cut short by a paragraph break",
+		"if[draft]{The quick\n\nbrown} fox",
+		[]Option{WithFlags(map[string]bool{"draft": false})},
+		"<p>fox</p>",
+	},
+	{
+		"condition cut short w/ flag set",
+		"- if[draft]{The quick\n- brown} fox",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<ul><li>The quick fox</li></ul>",
+	},
+	{
+		"condition w/ flag unset alone in its paragraph",
+		"if[draft]{The quick}\n\nbrown var[colour]\n\nfox",
+		[]Option{WithFlags(map[string]bool{"draft": false})},
+		"<p>brown</p><p>fox</p>",
+	},
+	{
+		"condition list markers read as text",
+		"if[draft]{- The quick\n- brown fox}",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<p>- The quick - brown fox</p>",
+	},
+	{
+		"condition flags case-sensitive",
+		"IF[draft]{The quick} if[Draft]{brown} fox",
+		[]Option{WithFlags(map[string]bool{"draft": true})},
+		"<p>The quick fox</p>",
+	},
+	{
+		"variable",
+		"The quick var[colour] fox",
+		[]Option{WithVariables(map[string]string{"colour": "brown"})},
+		"<p>The quick brown fox</p>",
+	},
+	{
+		"variable w/ value escaped",
+		"The var[speed] fox",
+		[]Option{WithVariables(map[string]string{"speed": "<quick & brown>"})},
+		"<p>The &lt;quick &amp; brown&gt; fox</p>",
+	},
+	{
+		"variable w/ unknown name",
+		"The quick var[colour] fox",
+		[]Option{WithVariables(map[string]string{"speed": "quick"})},
+		"<p>The quick fox</p>",
+	},
+	{
+		"variable inside tag",
+		"The quick bold[var[colour]] fox",
+		[]Option{WithVariables(map[string]string{"colour": "brown"})},
+		"<p>The quick <b>brown</b> fox</p>",
+	},
+	{
+		"empty tags dropped",
+		"The bold[] quick italic[bold[]] fox",
+		nil,
+		"<p>The quick fox</p>",
+	},
+	{
+		"empty tags as errors",
+		"The bold[] quick italic[bold[]] fox",
+		[]Option{WithEmptyTags(EmptyTagsError)},
+		"<p>The <span class='error'></span> quick <em><span class='error'></span></em> fox</p>",
+	},
+	{
+		"empty tags preserved",
+		"The bold[] quick italic[bold[]] fox",
+		[]Option{WithPreserveEmptyTags()},
+		"<p>The <b></b> quick <em><b></b></em> fox</p>",
+	},
+	{
+		"empty strikethrough shorthand preserved",
+		"The ~~~~ quick fox",
+		[]Option{WithStrikethroughShorthand(), WithPreserveEmptyTags()},
+		"<p>The <s></s> quick fox</p>",
+	},
+	{
+		"empty paragraph tag preserved",
+		"The quick paragraph[] brown fox",
+		[]Option{WithPreserveEmptyTags()},
+		"<p>The quick</p><p></p><p>brown fox</p>",
+	},
+	{
+		"max output bytes",
+		"The quick bold[brown fox] jumps\n\n- Item one\n- Item two",
+		[]Option{WithMaxOutputBytes(40)},
+		"<p>The quick <b>brown fox</b> jumps</p>",
+	},
+	{
+		"max output bytes not reached",
+		"The quick bold[brown fox] jumps",
+		[]Option{WithMaxOutputBytes(40)},
+		"<p>The quick <b>brown fox</b> jumps</p>",
+	},
+	{
+		"max paragraph length",
+		"The quick brown fox jumps over the lazy dog. It was not amused! The dog bold[barked loudly] and ran away over the hill, never to be seen again.",
+		[]Option{WithMaxParagraphLength(40)},
+		"<p>The quick brown fox jumps over the lazy</p><p>dog. It was not amused! The dog</p><p><b>barked loudly</b> and ran away over the</p><p>hill, never to be seen again.</p>",
+	},
+	{
+		"max paragraph length w/ sentence boundary",
+		"The quick brown fox jumps. Over the lazy dog it went.",
+		[]Option{WithMaxParagraphLength(40)},
+		"<p>The quick brown fox jumps.</p><p>Over the lazy dog it went.</p>",
+	},
+	{
+		"max paragraph length w/ short paragraph and list",
+		"The quick brown fox\n\n- jumps over the lazy dog and keeps going",
+		[]Option{WithMaxParagraphLength(20)},
+		"<p>The quick brown fox</p><ul><li>jumps over the lazy dog and keeps going</li></ul>",
+	},
+	{
+		"color",
+		"The color(red)[quick bold[brown]] fox",
+		[]Option{WithColorClasses(map[string]string{"red": "runic__color-red"})},
+		`<p>The <span class="runic__color-red">quick <b>brown</b></span> fox</p>`,
+	},
+	{
+		"color w/ unknown name",
+		"The color(blue)[quick] fox",
+		[]Option{WithColorClasses(map[string]string{"red": "runic__color-red"})},
+		"<p>The quick fox</p>",
+	},
+	{
+		"color w/ escaped class",
+		"The color(red)[quick] fox",
+		[]Option{WithColorClasses(map[string]string{"red": `red" onclick="x`})},
+		`<p>The <span class="red&#34; onclick=&#34;x">quick</span> fox</p>`,
+	},
+	{
+		"color w/o name",
+		"The color[quick] fox",
+		[]Option{WithColorClasses(map[string]string{"red": "runic__color-red"})},
+		"<p>The <span class='error'>quick</span> fox</p>",
+	},
+	{
+		"strikethrough shorthand",
+		"The quick ~~brown fox~~ jumps a ~ b",
+		[]Option{WithStrikethroughShorthand()},
+		"<p>The quick <s>brown fox</s> jumps a ~ b</p>",
+	},
+}
+
+func TestHtmlWithOptions(t *testing.T) {
+	for _, test := range htmlOptionTests {
+		testParser := New(test.opts...)
+		htmlString := testParser.Html(test.input)
+		if htmlString != test.expectedHtml {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, test.expectedHtml, htmlString)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+func TestSequentialCalls(t *testing.T) {
+	inputs := []string{
+		". This is a level one heading\nThe quick bold[brown fox] jumps over the lazy dog",
+		"- Item one\n  - Item two\n- Item three",
+		"The quick foo[brown fox jumps over the] lazy dog",
+	}
+	sharedParser := New()
+	for _, input := range inputs {
+		freshParser := New()
+		expectedHtml := freshParser.Html(input)
+		expectedHighlightText := freshParser.HighlightText(input)
+		for range 2 {
+			if htmlString := sharedParser.Html(input); htmlString != expectedHtml {
+				t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", input, expectedHtml, htmlString)
+			}
+			if highlightText := sharedParser.HighlightText(input); highlightText != expectedHighlightText {
+				t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", input, expectedHighlightText, highlightText)
+			}
+		}
+		if htmlString := renderHTML(sharedParser.Parse(input), newConfig()); htmlString != expectedHtml {
+			t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", input, expectedHtml, htmlString)
+		}
+	}
+}
+
+func TestHeadingIDPrefixFragments(t *testing.T) {
+	input := ". Introduction\n: Notes"
+	page := New(WithHeadingIDs(), WithHeadingIDPrefix("first-")).Html(input) +
+		New(WithHeadingIDs(), WithHeadingIDPrefix("second-")).Html(input)
+
+	ids := regexp.MustCompile(`id="([^"]*)"`).FindAllStringSubmatch(page, -1)
+	if len(ids) != 4 {
+		t.Fatalf("expected 4 ids, received %d in %s", len(ids), page)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id[1]] {
+			t.Errorf("duplicate id %q in %s", id[1], page)
+		}
+		seen[id[1]] = true
+	}
+}
+
+type highlightTextOptionTest struct {
+	name                  string
+	input                 string
+	opts                  []Option
+	expectedHighlightText string
+}
+
+var highlightTextOptionTests = []highlightTextOptionTest{
+	{
+		"tab indented list",
+		"\t- Item one\n\t\t- Item two",
+		nil,
+		`<span class="runic__bulletpoint">&nbsp;&nbsp;&nbsp;&nbsp;-&nbsp;</span><span class="runic__text">Item one<br>&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;</span><span class="runic__bulletpoint">-&nbsp;</span><span class="runic__text">Item two</span>`,
+	},
+	{
+		"tab indented list w/ tab size",
+		"\t- Item one\n\t\t- Item two",
+		[]Option{WithTabSize(2)},
+		`<span class="runic__bulletpoint">&nbsp;&nbsp;-&nbsp;</span><span class="runic__text">Item one<br>&nbsp;&nbsp;&nbsp;&nbsp;</span><span class="runic__bulletpoint">-&nbsp;</span><span class="runic__text">Item two</span>`,
+	},
+	{
+		"inner tab w/ tab size",
+		"The quick\tbrown fox",
+		[]Option{WithTabSize(3)},
+		`<span class="runic__text">The quick&nbsp;&nbsp;&nbsp;brown fox</span>`,
+	},
+	{
+		"inner tab w/ zero tab size",
+		"The quick\tbrown fox",
+		[]Option{WithTabSize(0)},
+		`<span class="runic__text">The quick&nbsp;&nbsp;&nbsp;&nbsp;brown fox</span>`,
+	},
+	{
+		"inner tab w/ negative tab size",
+		"The quick\tbrown fox",
+		[]Option{WithTabSize(-1)},
+		`<span class="runic__text">The quick&nbsp;&nbsp;&nbsp;&nbsp;brown fox</span>`,
+	},
+	{
+		"class prefix",
+		". Title\n\n- The bold[quick] link(url)[fox] if[x]{jumps}",
+		[]Option{WithClassPrefix("editor-")},
+		`<span class="editor-heading">.&nbsp;</span><span class="editor-text">Title<br></span><br>` +
+			`<span class="editor-bulletpoint">-&nbsp;</span><span class="editor-text">The&nbsp;</span><span class="editor-tag">bold</span><span class="editor-osq">[</span><span class="editor-text">quick</span><span class="editor-csq">]&nbsp;</span>` +
+			`<span class="editor-tag">link</span><span class="editor-arg">(url)</span><span class="editor-osq">[</span><span class="editor-text">fox</span><span class="editor-csq">]&nbsp;</span>` +
+			`<span class="editor-tag">if</span><span class="editor-osq">[</span><span class="editor-text">x</span><span class="editor-csq">]</span><span class="editor-ocb">{</span><span class="editor-text">jumps</span><span class="editor-ccb">}</span>`,
+	},
+}
+
+func TestHighlightTextWithOptions(t *testing.T) {
+	for _, test := range highlightTextOptionTests {
+		testParser := New(test.opts...)
+		highlightText := testParser.HighlightText(test.input)
+		if highlightText != test.expectedHighlightText {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, test.expectedHighlightText, highlightText)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+func TestNodeHtml(t *testing.T) {
+	testParser := New(WithListDepth())
+	tree := testParser.Parse("The quick brown fox\n\n- Item one\n  - Item two\n- Item three")
+
+	list := tree.Children[1]
+	expectedHtml := `<ul data-depth="0"><li>Item one<ul data-depth="1"><li>Item two</li></ul></li><li>Item three</li></ul>`
+	if htmlString := testParser.NodeHtml(list); htmlString != expectedHtml {
+		t.Errorf("list ERROR\nexpected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+
+	nestedList := list.Children[0].Children[1]
+	expectedHtml = `<ul data-depth="1"><li>Item two</li></ul>`
+	if htmlString := testParser.NodeHtml(nestedList); htmlString != expectedHtml {
+		t.Errorf("nested list ERROR\nexpected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+
+	looseParser := New(WithListLooseDetection())
+	looseList := looseParser.Parse("- Item one\n\n- Item two").Children[0]
+	expectedHtml = "<li><p>Item two</p></li>"
+	if htmlString := looseParser.NodeHtml(looseList.Children[1]); htmlString != expectedHtml {
+		t.Errorf("loose list item ERROR\nexpected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+}
+
+func TestWriteHtml(t *testing.T) {
+	for _, test := range htmlTests {
+		testParser := New()
+		var buf bytes.Buffer
+		n, err := testParser.WriteHtml(&buf, test.input)
+		if err != nil {
+			t.Errorf("%s ERROR\n%v", test.name, err)
+			continue
+		}
+		if htmlString := testParser.Html(test.input); buf.String() != htmlString || n != len(htmlString) {
+			t.Errorf("%s ERROR\nexpected: %s (%d bytes)\nreceived: %s (%d bytes)", test.name, htmlString, len(htmlString), buf.String(), n)
+		}
+	}
+	for _, test := range htmlOptionTests {
+		testParser := New(test.opts...)
+		var buf bytes.Buffer
+		testParser.WriteHtml(&buf, test.input)
+		if htmlString := testParser.Html(test.input); buf.String() != htmlString {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, htmlString, buf.String())
+		}
+	}
+}
+
+// failingWriter accepts up to `limit` bytes, then fails every write
+type failingWriter struct {
+	limit int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errWriteFailed
+	}
+	w.limit -= len(b)
+	return len(b), nil
+}
+
+func TestWriteHtmlError(t *testing.T) {
+	input := ". Title\n\nThe quick bold[brown fox] jumps over the lazy dog"
+	for _, limit := range []int{0, 10, 20} {
+		n, err := New().WriteHtml(&failingWriter{limit: limit}, input)
+		if !errors.Is(err, errWriteFailed) {
+			t.Errorf("limit %d ERROR\nexpected: %v\nreceived: %v", limit, errWriteFailed, err)
+		}
+		if n != limit {
+			t.Errorf("limit %d ERROR\nexpected: %d bytes\nreceived: %d bytes", limit, limit, n)
+		}
+	}
+}
+
+func TestWriteHtmlMaxOutputBytes(t *testing.T) {
+	// each tag renders to more bytes than it takes to write
+	input := strings.Repeat("bold[italic[a]] ", 100)
+	var buf bytes.Buffer
+	n, err := New(WithMaxOutputBytes(256)).WriteHtml(&buf, input)
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("expected: %v\nreceived: %v", ErrOutputTooLarge, err)
+	}
+	if n > 256 || n != buf.Len() {
+		t.Errorf("expected: at most 256 bytes\nreceived: %d bytes, %d written", n, buf.Len())
+	}
+	if !strings.HasPrefix(New().Html(input), buf.String()) {
+		t.Errorf("output is not a prefix of the full output\nreceived: %s", buf.String())
+	}
+}
+
+func TestHighlightJSON(t *testing.T) {
+	input := ". Title\n\nThe bold[fox]"
+	expectedJSON := `[` +
+		`{"class":"runic__heading","text":". ","start":0,"end":2},` +
+		`{"class":"runic__text","text":"Title\n","start":2,"end":8},` +
+		`{"class":"","text":"\n","start":8,"end":9},` +
+		`{"class":"runic__text","text":"The ","start":9,"end":13},` +
+		`{"class":"runic__tag","text":"bold","start":13,"end":17},` +
+		`{"class":"runic__osq","text":"[","start":17,"end":18},` +
+		`{"class":"runic__text","text":"fox","start":18,"end":21},` +
+		`{"class":"runic__csq","text":"]","start":21,"end":22}` +
+		`]`
+
+	highlightJSON, err := New().HighlightJSON(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(highlightJSON) != expectedJSON {
+		t.Errorf("expected: %s\nreceived: %s", expectedJSON, highlightJSON)
+	}
+
+	if highlightJSON, _ := New().HighlightJSON(""); string(highlightJSON) != "[]" {
+		t.Errorf("empty file ERROR\nexpected: []\nreceived: %s", highlightJSON)
+	}
+}
+
+func TestEditorData(t *testing.T) {
+	input := ". Title\n\nThe bold[quick] fox\n\n- Item one\n  - Item link(url)[two]"
+	testParser := New()
+	editorData, err := testParser.EditorData(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if editorData.Html != testParser.Html(input) || editorData.HighlightText != testParser.HighlightText(input) {
+		t.Errorf("expected: %s\n%s\nreceived: %s\n%s", testParser.Html(input), testParser.HighlightText(input), editorData.Html, editorData.HighlightText)
+	}
+
+	// node types the package doesn't know are kept as they are
+	editorData.Tree.AppendChild(&Node{Typ: "Custom", Val: "x"})
+	editorDataJSON, err := json.Marshal(editorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedEditorData, err := ParseEditorData(editorDataJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedEditorDataJSON, _ := json.Marshal(parsedEditorData); string(parsedEditorDataJSON) != string(editorDataJSON) {
+		t.Errorf("round trip ERROR\nexpected: %s\nreceived: %s", editorDataJSON, parsedEditorDataJSON)
+	}
+	Walk(parsedEditorData.Tree, func(n *Node) bool {
+		for _, child := range n.Children {
+			if child.Parent() != n {
+				t.Errorf("parent ERROR\nexpected: %s\nreceived: %v", n.Typ, child.Parent())
+			}
+		}
+		return true
+	})
+
+	if _, err := ParseEditorData([]byte("{")); err == nil {
+		t.Error("invalid JSON ERROR\nexpected: an error")
+	}
+}
+
+func TestEditorDataMaxOutputBytes(t *testing.T) {
+	editorData, err := New(WithMaxOutputBytes(10)).EditorData("The bold[quick] fox")
+	if !errors.Is(err, ErrOutputTooLarge) || editorData == nil || editorData.Html != "<p>The <b>" {
+		t.Errorf("expected: %q, %v\nreceived: %+v, %v", "<p>The <b>", ErrOutputTooLarge, editorData, err)
+	}
+}
+
+// BenchmarkHtmlNestedList renders lists of increasing size whose items nest
+// down to 16 levels deep and back again. the time per item should stay the
+// same as the list grows
+func BenchmarkHtmlNestedList(b *testing.B) {
+	for _, items := range []int{1 << 10, 4 << 10, 16 << 10} {
+		var input strings.Builder
+		for i := range items {
+			input.WriteString(strings.Repeat(" ", i%16*INDENT_WIDTH) + "- Item bold[" + strconv.Itoa(i) + "] text\n")
+		}
+		tree := New().Parse(input.String())
+		b.Run(strconv.Itoa(items), func(b *testing.B) {
+			for range b.N {
+				renderHTML(tree, newConfig())
+			}
+		})
+	}
+}
+
+func TestHtmlWithCombinedOptions(t *testing.T) {
+	testParser := New(WithSemanticTags(), WithHeadingIDs(), WithPreserveSpaces(), WithAutoLinks(), WithClassPrefix("editor-"))
+
+	input := ". The fox\nThe bold[quick] brown     fox at https://example.com."
+	expectedHtml := `<h1 id="the-fox">The fox</h1><p>The <strong>quick</strong> brown&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;fox at <a href="https://example.com">https://example.com</a>.</p>`
+	if htmlString := testParser.Html(input); htmlString != expectedHtml {
+		t.Errorf("html ERROR\nexpected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+
+	expectedHighlightText := `<span class="editor-tag">bold</span><span class="editor-osq">[</span><span class="editor-text">quick</span><span class="editor-csq">]</span>`
+	if highlightText := testParser.HighlightText("bold[quick]"); highlightText != expectedHighlightText {
+		t.Errorf("highlight ERROR\nexpected: %s\nreceived: %s", expectedHighlightText, highlightText)
+	}
+}
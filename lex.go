@@ -10,46 +10,77 @@ import (
 
 // token represents a single lexeme returned from the lexer
 type token struct {
-	Typ    tokenType `json:"type"` // type of token
-	Val    string    `json:"val"`  // characters comprising the token
-	Line   int       `json:"line"` // line where the token was found
-	Pos    int       `json:"pos"`  // position in the input text where the token was found
-	indent int       // number of spaces appearing before the token
+	Typ       tokenType `json:"type"` // type of token
+	Val       string    `json:"val"`  // characters comprising the token
+	Line      int       `json:"line"` // line where the token was found
+	Pos       int       `json:"pos"`  // position in the input text where the token was found
+	indent    int       // number of spaces appearing before the token
+	blankLine bool      // a blank line separates the token from the one before it
 }
 
 func (t *token) String() string {
-	var tokenTypeString string
-	switch t.Typ {
+	var indent string
+	if t.indent > 0 {
+		indent = ">" + strconv.Itoa(t.indent)
+	}
+	if t.blankLine {
+		indent += "^"
+	}
+	return fmt.Sprintf("[%d:%d:%s:'%s'%s]", t.Line, t.Pos, t.Typ, t.Val, indent)
+}
+
+type tokenType int
+
+// String returns the name of the token type's constant, e.g. "typeText", or
+// "tokenType(n)" for a value without one
+func (t tokenType) String() string {
+	switch t {
 	case typeNone:
-		tokenTypeString = "typeNone"
+		return "typeNone"
 	case typeEOF:
-		tokenTypeString = "typeEOF"
+		return "typeEOF"
 	case typeText:
-		tokenTypeString = "typeText"
+		return "typeText"
 	case typeTerminator:
-		tokenTypeString = "typeTerminator"
+		return "typeTerminator"
 	case typeHeading:
-		tokenTypeString = "typeHeading"
+		return "typeHeading"
 	case typeTag:
-		tokenTypeString = "typeTag"
+		return "typeTag"
 	case typeOpeningSquare:
-		tokenTypeString = "typeOpeningSquare"
+		return "typeOpeningSquare"
 	case typeClosingSquare:
-		tokenTypeString = "typeClosingSquare"
+		return "typeClosingSquare"
 	case typeBulletpoint:
-		tokenTypeString = "typeBulletpoint"
+		return "typeBulletpoint"
+	case typeOpeningCurly:
+		return "typeOpeningCurly"
+	case typeClosingCurly:
+		return "typeClosingCurly"
+	case typeStrike:
+		return "typeStrike"
+	case typeTagArg:
+		return "typeTagArg"
+	case typeNumberpoint:
+		return "typeNumberpoint"
+	case typeQuote:
+		return "typeQuote"
+	case typeLineBreak:
+		return "typeLineBreak"
+	case typeCheckbox:
+		return "typeCheckbox"
+	case typeComment:
+		return "typeComment"
+	case typeFrontmatter:
+		return "typeFrontmatter"
+	case typeRule:
+		return "typeRule"
 	}
-	var indent string
-	if t.indent > 0 {
-		indent = ">" + strconv.Itoa(t.indent)
-	}
-	return fmt.Sprintf("[%d:%d:%s:'%s'%s]", t.Line, t.Pos, tokenTypeString, t.Val, indent)
+	return fmt.Sprintf("tokenType(%d)", int(t))
 }
 
-type tokenType int
-
 const (
-	typeNone = iota
+	typeNone tokenType = iota
 	typeEOF
 	typeText
 	typeTerminator
@@ -58,52 +89,142 @@ const (
 	typeOpeningSquare
 	typeClosingSquare
 	typeBulletpoint
+	typeOpeningCurly
+	typeClosingCurly
+	typeStrike
+	typeTagArg
+	typeNumberpoint
+	typeQuote
+	typeLineBreak
+	typeCheckbox
+	typeComment
+	typeFrontmatter
+	typeRule
 )
 
+// lineEndings holds the characters which may end a line in the input
+const lineEndings = "\r\n"
+
 const (
 	eof               = -1
 	void              = -2
 	charNewline       = '\n'
+	charReturn        = '\r'
 	charDot           = '.'
 	charColon         = ':'
 	charOpeningSquare = '['
 	charClosingSquare = ']'
 	charBackslash     = '\\'
 	charHyphen        = '-'
+	charOpeningCurly  = '{'
+	charClosingCurly  = '}'
+	charTilde         = '~'
+	charOpeningParen  = '('
+	charClosingParen  = ')'
+	charQuote         = '>'
+	charEquals        = '='
+)
+
+// strikeShorthand opens and closes a strike tag when enabled by
+// `WithStrikethroughShorthand`
+const strikeShorthand = "~~"
+
+// lineBreakMarker ends a line of a paragraph with a hard break, where the
+// newline would otherwise be read as a space
+const lineBreakMarker = `\\`
+
+// commentMarker begins a line which is left out of the output, as in
+// `// a note for authors`. like a rule, a comment on the line after a block
+// ends it
+const commentMarker = "//"
+
+// frontmatterDelimiter opens and closes the metadata at the start of the
+// input, each on a line by itself, as in `---\ntitle: Runic\n---`
+const frontmatterDelimiter = "---"
+
+// the checkboxes which may begin the text of a list item, making it a task
+const (
+	checkboxUnchecked = "[ ]"
+	checkboxChecked   = "[x]"
+)
+
+// tagCondition is the tag whose closing square may be followed by a `{...}`
+// block, as in `if[flag]{...}`
+const tagCondition = "if"
+
+// tagVariable is the tag substituted with a value from `WithVariables`, as in
+// `var[name]`
+const tagVariable = "var"
+
+// tagLink, tagImage and tagColor take a parenthesised argument before their
+// opening square, as in `link(url)[text]`
+const (
+	tagLink  = "link"
+	tagImage = "image"
+	tagColor = "color"
 )
 
+// hasArg reports whether the tag takes a parenthesised argument
+func hasArg(tag string) bool {
+	name := canonicalTag(tag)
+	return name == tagLink || name == tagImage || name == tagColor
+}
+
+// canonicalTag returns the name a tag is matched by. tag names are case
+// insensitive, so `Bold[...]` is read as `bold[...]`
+func canonicalTag(tag string) string {
+	return strings.ToLower(tag)
+}
+
+// ruleMinLength is the number of `-` or `=` characters a line needs to be read
+// as a horizontal rule rather than a list item, as in `---`
+const ruleMinLength = 3
+
+// tagCode is the tag whose content is kept as written, without parsing the
+// tags inside it, as in `code[bold[x]]`
+const tagCode = "code"
+
+// tagParagraph wraps its content in a paragraph of its own, whatever text is
+// around it, as in `paragraph[...]`
+const tagParagraph = "paragraph"
+
 // lexer represents the state machine processing the input text
 type lexer struct {
-	input             string  // input string containing markup
+	input             string  // input string containing markup, never modified
 	line              int     // current line number
 	pos               int     // current position in the input text
 	token             token   // current token
 	char              rune    // current character
 	lexNext           func()  // next lex function
 	skippedNewlines   int     // number of newlines skipped during `skipSpace`
-	skippedSpace      int     // number of spaces skipped during `skipSpace`
 	tag               string  // current tag accumulated (run of `unicode.isLetter` chars)
 	continuousNewline bool    // don't treat a single newline as a terminator
 	ctx               ctxType // current context
+	cfg               config  // settings provided by the parser
+	condition         bool    // the current tag is a `tagCondition`
+	curlyDepth        int     // number of unclosed `{` blocks
+	emptyParagraphs   int     // number of empty paragraph terminators left to lex
+	collapsed         int     // offset of the whitespace rune read as a newline, see `skipSpace`
 }
 
 type ctxType int
 
 const (
 	ctxList ctxType = iota + 1
+	ctxQuote
 )
 
 // lex returns a lexer, initialised to process the given input text
-func lex(input string) *lexer {
-	l := &lexer{input: input, line: 1}
-	l.lexNext = l.lexGlobal
+func lex(input string, cfg config) *lexer {
+	l := &lexer{input: input, line: 1, cfg: cfg, collapsed: -1}
+	l.lexNext = l.lexFrontmatter
 	return l
 }
 
 // collectTokens runs the lexer and accumulates the lexed tokens to return,
 // used by the test suite
-func collectTokens(input string) (tokens []token) {
-	lexer := lex(input)
+func collectTokens(input string, opts ...Option) (tokens []token) {
+	lexer := lex(input, newConfig(opts...))
 	for lexer.nextToken() {
 		tokens = append(tokens, lexer.token)
 	}
@@ -143,11 +264,10 @@ func (l *lexer) next() {
 		l.char = eof
 		return
 	}
-	char, byteWidth := utf8.DecodeRuneInString(l.input[l.pos:])
+	char, byteWidth := l.runeAt(l.pos)
 	l.pos += byteWidth
 	if char == charNewline {
 		l.line++
-		l.skippedSpace = 0
 	}
 	l.char = char
 	l.addToTag()
@@ -162,12 +282,48 @@ func (l *lexer) backup() {
 	}
 	if l.char == charNewline {
 		l.line--
+		// the newline is counted again when it is next read
+		if l.skippedNewlines > 0 {
+			l.skippedNewlines--
+		}
 	}
-	char, byteWidth := utf8.DecodeLastRuneInString(l.input[:l.pos])
+	char, byteWidth := l.runeBefore(l.pos)
 	l.pos -= byteWidth
 	l.char = char
 }
 
+// runeAt decodes the rune starting at the given offset in the input. a `\r\n`
+// or lone `\r` line ending is read as a single newline, as is the whitespace
+// rune collapsed by `skipSpace`
+func (l *lexer) runeAt(pos int) (rune, int) {
+	char, byteWidth := utf8.DecodeRuneInString(l.input[pos:])
+	if char == charReturn {
+		char = charNewline
+		if strings.HasPrefix(l.input[pos+1:], string(charNewline)) {
+			byteWidth++
+		}
+	}
+	if pos == l.collapsed {
+		return charNewline, byteWidth
+	}
+	return char, byteWidth
+}
+
+// runeBefore decodes the rune ending at the given offset in the input, like
+// `runeAt`
+func (l *lexer) runeBefore(pos int) (rune, int) {
+	char, byteWidth := utf8.DecodeLastRuneInString(l.input[:pos])
+	if char == charReturn {
+		char = charNewline
+	} else if char == charNewline && strings.HasSuffix(l.input[:pos-1], string(charReturn)) {
+		byteWidth++
+	}
+	if pos-byteWidth == l.collapsed {
+		return charNewline, byteWidth
+	}
+	return char, byteWidth
+}
+
 func (l *lexer) nextN(n int) {
 	for range n {
 		l.next()
@@ -182,12 +338,13 @@ func (l *lexer) backupN(n int) {
 
 // skipSpace checks that the current and proceeding characters are both
 // whitespace. if they are, the lexer progresses to the next character. if a
-// newline has been skipped at any point, the final whitespace character is
-// replaced in the input with a newline. the result is that any combination of
-// whitespace which includes a newline is reduced to a single newline character
+// newline has been skipped at any point, the final whitespace character is read
+// as a newline, from now on and if the lexer backs up over it. the result is
+// that any combination of whitespace which includes a newline is reduced to a
+// single newline character. the input itself is left alone, since rewriting it
+// copies the whole string for every run of whitespace
 func (l *lexer) skipSpace() {
 	if unicode.IsSpace(l.char) && unicode.IsSpace(l.peek()) {
-		l.skippedSpace++
 		if l.char == charNewline {
 			l.skippedNewlines++
 		}
@@ -198,7 +355,8 @@ func (l *lexer) skipSpace() {
 		if l.char == charNewline {
 			l.skippedNewlines++
 		}
-		l.input = l.input[:l.pos-1] + "\n" + l.input[l.pos:]
+		_, byteWidth := l.runeBefore(l.pos)
+		l.collapsed = l.pos - byteWidth
 		l.char = charNewline
 		return
 	}
@@ -206,7 +364,8 @@ func (l *lexer) skipSpace() {
 }
 
 // addToTag stores the currently lexing tag in `l.tag`. a "tag" is a group of
-// a-z characters, typically found before a `charOpeningSquare`
+// letters, typically found before a `charOpeningSquare`. any other character
+// discards the group
 func (l *lexer) addToTag() {
 	if l.token.Typ != typeText {
 		return
@@ -215,7 +374,11 @@ func (l *lexer) addToTag() {
 		l.tag += string(l.char)
 		return
 	}
-	if unicode.IsSpace(l.char) {
+	// `lexText` decides whether the paren begins an argument
+	if l.char == charOpeningParen && hasArg(l.tag) {
+		return
+	}
+	if l.char != charOpeningSquare {
 		l.tag = ""
 	}
 }
@@ -225,7 +388,7 @@ func (l *lexer) peek() rune {
 	if l.pos == len(l.input) {
 		return eof
 	}
-	char, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	char, _ := l.runeAt(l.pos)
 	return char
 }
 
@@ -234,22 +397,76 @@ func (l *lexer) peekBehind() rune {
 	if l.pos == 0 {
 		return void
 	}
-	if l.pos == len(l.input) {
-		char, _ := utf8.DecodeLastRuneInString(l.input)
+	// past the end of the input, the previous rune is the last one
+	if l.char == eof {
+		char, _ := l.runeBefore(l.pos)
 		return char
 	}
-	char, _ := utf8.DecodeLastRuneInString(l.input[:l.pos-1])
+	_, byteWidth := l.runeBefore(l.pos)
+	if l.pos == byteWidth {
+		return void
+	}
+	char, _ := l.runeBefore(l.pos - byteWidth)
 	return char
 }
 
+// peekNextNonSpace reads the next non-whitespace rune in the input but does not
+// progress the lexer. it returns eof if only whitespace remains
 func (l *lexer) peekNextNonSpace() rune {
-	if l.pos == len(l.input) {
+	rest := strings.TrimSpace(l.input[l.pos:])
+	if len(rest) == 0 {
 		return eof
 	}
-	char, _ := utf8.DecodeRuneInString(strings.TrimSpace(l.input[l.pos:]))
+	char, _ := utf8.DecodeRuneInString(rest)
 	return char
 }
 
+// indentAt returns the width of the whitespace between the start of the line
+// and the given position in the input. tabs advance to the next tab stop, every
+// `tabSize` columns, so mixed tabs and spaces produce a consistent width
+func (l *lexer) indentAt(pos int) (width int) {
+	lineStart := strings.LastIndexAny(l.input[:pos], lineEndings) + 1
+	for _, char := range l.input[lineStart:pos] {
+		switch {
+		case char == '\t':
+			width += l.cfg.tabSize - width%l.cfg.tabSize
+		case unicode.IsSpace(char):
+			width++
+		default:
+			width = 0
+		}
+	}
+	return
+}
+
+// isJoined reports whether the given token directly follows a non-whitespace
+// character in the input, as in `fox\bold[jumps]`, or an escaped newline which
+// joins it to the line before
+func (l *lexer) isJoined(t token) bool {
+	if t.Pos == 0 {
+		return false
+	}
+	char, _ := l.runeBefore(t.Pos)
+	if !unicode.IsSpace(char) {
+		return true
+	}
+
+	before := strings.TrimRightFunc(l.input[:t.Pos], unicode.IsSpace)
+	escaped, ok := strings.CutSuffix(before, string(charBackslash))
+	if !ok || strings.IndexAny(l.input[len(before):], lineEndings) != 0 {
+		return false
+	}
+	char, _ = utf8.DecodeLastRuneInString(escaped)
+	return char != charBackslash && !unicode.IsSpace(char)
+}
+
+// tokenEnd returns the offset just past the current token, leaving out any
+// whitespace the lexer has already read beyond it
+func (l *lexer) tokenEnd() int {
+	read := l.input[l.token.Pos:max(l.pos, l.token.Pos)]
+	return l.token.Pos + len(strings.TrimRightFunc(read, unicode.IsSpace))
+}
+
 func (l *lexer) addToToken(c rune) {
 	l.token.Val += string(c)
 }
@@ -259,11 +476,16 @@ func (l *lexer) truncateToken(n int) {
 }
 
 func (l *lexer) trimTrailingSpace() {
-	if len(l.token.Val) == 0 {
+	// the token is checked rather than the input, which may hold an escaped
+	// newline that was never added to it. only `WithPreserveSpaces` leaves more
+	// than one space to trim
+	if l.cfg.preserveSpaces {
+		l.token.Val = strings.TrimRightFunc(l.token.Val, unicode.IsSpace)
 		return
 	}
-	if unicode.IsSpace(l.peekBehind()) {
-		l.token.Val = l.token.Val[:len(l.token.Val)-1]
+	char, byteWidth := utf8.DecodeLastRuneInString(l.token.Val)
+	if unicode.IsSpace(char) {
+		l.token.Val = l.token.Val[:len(l.token.Val)-byteWidth]
 	}
 }
 
@@ -274,7 +496,48 @@ func (l *lexer) isHeadingChar() bool {
 	return false
 }
 
-// lexGlobal is the starting state of the lexer
+// frontmatterLength returns the length of the frontmatter at the start of the
+// input, up to the end of its closing delimiter, or 0 if the input doesn't
+// begin with one. frontmatter which is never closed is read as markup
+func frontmatterLength(input string) int {
+	pos := 0
+	for line := 0; pos < len(input); line++ {
+		end := strings.IndexAny(input[pos:], lineEndings)
+		if end < 0 {
+			end = len(input) - pos
+		}
+		delimiter := strings.TrimRight(input[pos:pos+end], " \t") == frontmatterDelimiter
+		if line == 0 && !delimiter {
+			return 0
+		}
+		if line > 0 && delimiter {
+			return pos + end
+		}
+		// past the line ending, where `\r\n` is a single one
+		pos += end
+		if strings.HasPrefix(input[pos:], "\r\n") {
+			pos++
+		}
+		pos++
+	}
+	return 0
+}
+
+// lexFrontmatter is the state the lexer starts in. it lexes the frontmatter
+// opening the input, if there is one, and continues with `lexGlobal`
+func (l *lexer) lexFrontmatter() {
+	l.lexNext = l.lexGlobal
+	length := frontmatterLength(l.input)
+	if length == 0 {
+		return
+	}
+	l.token = l.mkToken(typeFrontmatter, l.input[:length])
+	for l.pos < length {
+		l.next()
+	}
+}
+
+// lexGlobal is the state of the lexer at the start of each block
 func (l *lexer) lexGlobal() {
 	l.resetToken()
 	l.next()
@@ -287,8 +550,10 @@ func (l *lexer) lexGlobal() {
 		l.lexNext = l.lexGlobal
 		return
 	}
+	// an escaped character always begins a paragraph
 	if l.char == charBackslash {
 		l.backup()
+		l.continuousNewline = true
 		l.lexNext = l.lexText
 		return
 	}
@@ -297,12 +562,29 @@ func (l *lexer) lexGlobal() {
 		l.lexNext = l.lexHeading
 		return
 	}
-	if l.char == charHyphen {
+	if l.char == charHyphen && ruleLength(l.input[l.pos-1:]) == 0 {
 		l.backup()
 		l.lexNext = l.lexHypen
 		return
 	}
+	if l.char == charQuote {
+		l.backup()
+		l.lexNext = l.lexQuote
+		return
+	}
 	l.backup()
+	if strings.HasPrefix(l.input[l.pos:], commentMarker) {
+		l.lexNext = l.lexComment
+		return
+	}
+	if ruleLength(l.input[l.pos:]) > 0 {
+		l.lexNext = l.lexRule
+		return
+	}
+	if numberpointLength(l.input[l.pos:]) > 0 {
+		l.lexNext = l.lexNumberpoint
+		return
+	}
 	l.continuousNewline = true
 	l.lexNext = l.lexText
 }
@@ -310,6 +592,7 @@ func (l *lexer) lexGlobal() {
 // lexText is used to parse standard text
 func (l *lexer) lexText() {
 	l.token = l.mkToken(typeText, "")
+	l.tag = ""
 	defer func() {
 		if l.token.Val == "" {
 			l.token.Typ = typeNone
@@ -322,6 +605,21 @@ func (l *lexer) lexText() {
 			l.lexNext = l.lexGlobal
 			return
 		}
+		if l.char == charBackslash && l.peekBehind() != charBackslash && l.isLineBreak() {
+			l.trimTrailingSpace()
+			l.backup()
+			l.lexNext = l.lexLineBreak
+			return
+		}
+		// an escaped newline joins the surrounding lines without a space, unless
+		// the whitespace it begins would otherwise end the paragraph
+		if l.char == charBackslash && l.peek() == charNewline && l.peekBehind() != charBackslash {
+			l.tag = ""
+			l.next()
+			if l.skippedNewlines < l.cfg.paragraphBreak {
+				continue
+			}
+		}
 		if l.char == charBackslash && l.peek() != charBackslash {
 			l.tag = ""
 			continue
@@ -330,18 +628,44 @@ func (l *lexer) lexText() {
 			l.addToToken(l.char)
 			continue
 		}
+		// unlike the markers of other blocks, a rule or a comment ends the block
+		// it follows
+		if l.char == charNewline && isBlockEnd(strings.TrimLeft(l.input[l.pos:], " \t")) {
+			l.lexNext = l.lexTerminator
+			return
+		}
 		if l.char == charNewline && l.ctx == ctxList {
 			if l.peekNextNonSpace() == charHyphen {
 				l.lexNext = l.lexHypen
 				return
 			}
+			if numberpointLength(strings.TrimLeftFunc(l.input[l.pos:], unicode.IsSpace)) > 0 {
+				l.lexNext = l.lexNumberpoint
+				return
+			}
 			l.lexNext = l.lexTerminator
 			return
 		}
-		if l.char == charNewline && (!l.continuousNewline || l.skippedNewlines >= 2) {
+		// consecutive quote lines continue the quote, anything else ends it
+		if l.char == charNewline && l.ctx == ctxQuote {
+			if l.peekNextNonSpace() == charQuote && l.skippedNewlines < 2 {
+				l.lexNext = l.lexQuote
+				return
+			}
 			l.lexNext = l.lexTerminator
 			return
 		}
+		if l.char == charNewline && (!l.continuousNewline || l.newlines() >= l.cfg.paragraphBreak) {
+			l.lexNext = l.lexTerminator
+			return
+		}
+		// a newline ending the input is never read as a line break
+		if l.char == charNewline && l.cfg.hardLineBreaks && strings.TrimSpace(l.input[l.pos:]) != "" {
+			l.trimTrailingSpace()
+			l.backup()
+			l.lexNext = l.lexNewlineBreak
+			return
+		}
 		if l.char == charNewline && l.continuousNewline {
 			l.addToToken(' ')
 			continue
@@ -353,7 +677,20 @@ func (l *lexer) lexText() {
 				l.lexNext = l.lexOpeningSquare
 				return
 			}
-			l.backupN(len(l.tag))
+			l.backupN(utf8.RuneCountInString(l.tag))
+			l.truncateToken(len(l.tag))
+			l.trimTrailingSpace()
+			l.backup()
+			l.lexNext = l.lexTag
+			return
+		}
+		if l.char == charOpeningParen && hasArg(l.tag) {
+			if !l.hasTagArg() {
+				l.tag = ""
+				l.addToToken(l.char)
+				continue
+			}
+			l.backupN(utf8.RuneCountInString(l.tag))
 			l.truncateToken(len(l.tag))
 			l.trimTrailingSpace()
 			l.backup()
@@ -366,14 +703,104 @@ func (l *lexer) lexText() {
 			l.lexNext = l.lexClosingSquare
 			return
 		}
+		if l.cfg.strikeShorthand && l.char == charTilde && l.peek() == charTilde {
+			l.trimTrailingSpace()
+			l.backup()
+			l.lexNext = l.lexStrike
+			return
+		}
+		if l.char == charClosingCurly && l.curlyDepth > 0 {
+			l.trimTrailingSpace()
+			l.backup()
+			l.lexNext = l.lexClosingCurly
+			return
+		}
 		if l.token.Val == "" && unicode.IsSpace(l.char) {
 			l.token = l.mkToken(typeText, "")
+			// `WithPreserveSpaces` keeps a run of spaces after a tag, which
+			// is then joined to it
+			if run := l.spacesAfterTag(); l.cfg.preserveSpaces && len(run) > 1 {
+				l.token.Val, l.token.Pos = run, l.pos-len(run)
+			}
+			continue
+		}
+		// `skipSpace` has passed over all but the last space of a run, which
+		// `WithPreserveSpaces` keeps in full
+		if l.cfg.preserveSpaces && l.char == ' ' {
+			run := len(l.input[:l.pos]) - len(strings.TrimRight(l.input[:l.pos], " "))
+			l.token.Val += strings.Repeat(" ", run)
 			continue
 		}
 		l.addToToken(l.char)
 	}
 }
 
+// spacesAfterTag returns the run of spaces ending with the one just read when
+// it directly follows the closing square or curly of a tag
+func (l *lexer) spacesAfterTag() string {
+	before := strings.TrimRight(l.input[:l.pos], " ")
+	if !strings.HasSuffix(before, string(charClosingSquare)) && !strings.HasSuffix(before, string(charClosingCurly)) {
+		return ""
+	}
+	return l.input[len(before):l.pos]
+}
+
+// isLineBreak reports whether the `charBackslash` just read begins a
+// `lineBreakMarker` at the end of a line, followed by more of the same
+// paragraph on the next
+func (l *lexer) isLineBreak() bool {
+	if !l.continuousNewline || l.ctx != 0 {
+		return false
+	}
+	rest, ok := strings.CutPrefix(l.input[l.pos:], lineBreakMarker[1:])
+	if !ok || strings.IndexAny(rest, lineEndings) != 0 {
+		return false
+	}
+	next := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	space := rest[:len(rest)-len(next)]
+	newlines := strings.Count(space, "\n") + strings.Count(space, "\r") - strings.Count(space, "\r\n")
+	return next != "" && newlines < l.cfg.paragraphBreak
+}
+
+// lexLineBreak lexes a `lineBreakMarker` along with the newline after it, and
+// returns to `lexText` for the rest of the paragraph
+func (l *lexer) lexLineBreak() {
+	l.token = l.mkToken(typeLineBreak, lineBreakMarker)
+	l.nextN(len(lineBreakMarker))
+	l.next()
+	l.lexNext = l.lexText
+}
+
+// lexNewlineBreak lexes a single newline read as a line break by
+// `WithHardLineBreaks`, and returns to `lexText` for the rest of the paragraph
+func (l *lexer) lexNewlineBreak() {
+	l.token = l.mkToken(typeLineBreak, string(charNewline))
+	l.next()
+	l.lexNext = l.lexText
+}
+
+// lexComment lexes a comment, as written up to the end of its line, and
+// returns to `lexGlobal`
+func (l *lexer) lexComment() {
+	l.token = l.mkToken(typeComment, "")
+	end := strings.IndexAny(l.input[l.pos:], lineEndings)
+	if end < 0 {
+		end = len(l.input) - l.pos
+	}
+	l.token.Val = strings.TrimRightFunc(l.input[l.pos:l.pos+end], unicode.IsSpace)
+	l.pos += end
+	l.lexNext = l.lexGlobal
+}
+
+// lexRule lexes a horizontal rule, as written up to the end of its line, and
+// returns to `lexGlobal`
+func (l *lexer) lexRule() {
+	end := ruleLength(l.input[l.pos:])
+	l.token = l.mkToken(typeRule, strings.TrimRightFunc(l.input[l.pos:l.pos+end], unicode.IsSpace))
+	l.pos += end
+	l.lexNext = l.lexGlobal
+}
+
 // lexHeading lexes the heading symbols and returns to `lexText`
 func (l *lexer) lexHeading() {
 	l.token = l.mkToken(typeHeading, "")
@@ -383,7 +810,7 @@ func (l *lexer) lexHeading() {
 			l.lexNext = l.lexGlobal
 			return
 		}
-		if !l.isHeadingChar() || len(l.token.Val) == 3 {
+		if !l.isHeadingChar() {
 			l.backup()
 			l.lexNext = l.lexText
 			return
@@ -394,17 +821,66 @@ func (l *lexer) lexHeading() {
 
 // lexTerminator is intended to produce a terminator token and return to `lexGlobal`
 func (l *lexer) lexTerminator() {
+	newlines := l.newlines()
 	l.backup()
 	l.token = l.mkToken(typeTerminator, string(charNewline))
 	l.next()
 	l.lexNext = l.lexGlobal
+
+	// each newline beyond those ending the block is an empty paragraph, unless
+	// the input ends in them
+	if l.cfg.emptyParagraphs && strings.TrimSpace(l.input[l.pos:]) != "" {
+		l.emptyParagraphs = newlines - l.cfg.paragraphBreak
+		if l.emptyParagraphs > 0 {
+			l.lexNext = l.lexEmptyParagraph
+		}
+	}
+}
+
+// lexEmptyParagraph lexes a terminator for each paragraph preserved by
+// `WithPreserveEmptyParagraphs`
+func (l *lexer) lexEmptyParagraph() {
+	l.token = l.mkToken(typeTerminator, string(charNewline))
+	l.emptyParagraphs--
+	if l.emptyParagraphs == 0 {
+		l.lexNext = l.lexGlobal
+	}
+}
+
+// newlines returns the number of newlines in the whitespace ending at the
+// current `charNewline`
+func (l *lexer) newlines() int {
+	return max(l.skippedNewlines, 1)
 }
 
 func (l *lexer) lexTag() {
 	l.token = l.mkToken(typeTag, l.tag)
-	l.nextN(len(l.tag))
+	l.condition = canonicalTag(l.tag) == tagCondition
+	l.nextN(utf8.RuneCountInString(l.tag))
 	l.tag = ""
 	l.lexNext = l.lexOpeningSquare
+	if l.peek() == charOpeningParen {
+		l.lexNext = l.lexTagArg
+	}
+}
+
+// hasTagArg reports whether the `charOpeningParen` just read begins a tag
+// argument, closed on the same line and directly followed by an opening square
+func (l *lexer) hasTagArg() bool {
+	rest := l.input[l.pos:]
+	end := strings.IndexAny(rest, string(charClosingParen)+lineEndings)
+	return end >= 0 && rest[end] == charClosingParen && strings.HasPrefix(rest[end+1:], string(charOpeningSquare))
+}
+
+// lexTagArg lexes the argument between a tag and its opening square, as in
+// `link(url)[text]`. `hasTagArg` already checked it's closed on the same line
+func (l *lexer) lexTagArg() {
+	l.token = l.mkToken(typeTagArg, "")
+	end := l.pos + strings.IndexByte(l.input[l.pos:], charClosingParen)
+	l.token.Val = strings.TrimSpace(l.input[l.pos+1 : end])
+	l.pos = end + 1
+	l.char = charClosingParen
+	l.lexNext = l.lexOpeningSquare
 }
 
 func (l *lexer) lexOpeningSquare() {
@@ -417,15 +893,136 @@ func (l *lexer) lexClosingSquare() {
 	l.token = l.mkToken(typeClosingSquare, string(charClosingSquare))
 	l.next()
 	l.lexNext = l.lexText
+	if l.condition && l.peek() == charOpeningCurly {
+		l.lexNext = l.lexOpeningCurly
+	}
+	l.condition = false
+}
+
+func (l *lexer) lexOpeningCurly() {
+	l.token = l.mkToken(typeOpeningCurly, string(charOpeningCurly))
+	l.next()
+	l.tag = ""
+	l.curlyDepth++
+	l.lexNext = l.lexText
+}
+
+func (l *lexer) lexClosingCurly() {
+	l.token = l.mkToken(typeClosingCurly, string(charClosingCurly))
+	l.next()
+	l.curlyDepth--
+	l.lexNext = l.lexText
+}
+
+func (l *lexer) lexStrike() {
+	l.token = l.mkToken(typeStrike, strikeShorthand)
+	l.nextN(len(strikeShorthand))
+	l.tag = ""
+	l.lexNext = l.lexText
 }
 
 func (l *lexer) lexHypen() {
 	l.token = l.mkToken(typeBulletpoint, "-")
-	l.token.indent = l.skippedSpace
+	l.token.indent = l.indentAt(l.pos)
+	l.token.blankLine = l.skippedNewlines >= 2
 	l.next()
 	if unicode.IsSpace(l.char) {
 		l.next()
 	}
 	l.ctx = ctxList
+	l.lexNext = l.lexCheckbox
+}
+
+// lexQuote lexes the `charQuote` beginning a line of a blockquote
+func (l *lexer) lexQuote() {
+	l.token = l.mkToken(typeQuote, string(charQuote))
+	l.next()
+	l.ctx = ctxQuote
 	l.lexNext = l.lexText
 }
+
+// isBlockEnd reports whether the line beginning the input ends the block on
+// the line before it, being a rule or a comment
+func isBlockEnd(input string) bool {
+	return ruleLength(input) > 0 || strings.HasPrefix(input, commentMarker)
+}
+
+// ruleLength returns the length of the line beginning the input if it is a
+// horizontal rule, being `ruleMinLength` or more of the same `-` or `=`
+// character and nothing else besides trailing whitespace, or 0 otherwise
+func ruleLength(input string) int {
+	end := strings.IndexAny(input, lineEndings)
+	if end < 0 {
+		end = len(input)
+	}
+	line := strings.TrimRight(input[:end], " \t")
+	if len(line) < ruleMinLength || (line[0] != charHyphen && line[0] != charEquals) {
+		return 0
+	}
+	if strings.Trim(line, line[:1]) != "" {
+		return 0
+	}
+	return end
+}
+
+// numberpointLength returns the length of the ordered list marker at the start
+// of the input, such as `12.`, or 0 if there isn't one. the marker must be
+// followed by whitespace or the end of the input
+func numberpointLength(input string) int {
+	digits := len(input) - len(strings.TrimLeft(input, "0123456789"))
+	if digits == 0 || !strings.HasPrefix(input[digits:], string(charDot)) {
+		return 0
+	}
+	rest := input[digits+1:]
+	if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+		return 0
+	}
+	return digits + 1
+}
+
+func (l *lexer) lexNumberpoint() {
+	marker := l.input[l.pos : l.pos+numberpointLength(l.input[l.pos:])]
+	l.token = l.mkToken(typeNumberpoint, marker)
+	l.token.indent = l.indentAt(l.pos)
+	l.token.blankLine = l.skippedNewlines >= 2
+	l.nextN(len(marker))
+	if unicode.IsSpace(l.peek()) {
+		l.next()
+	}
+	l.ctx = ctxList
+	l.lexNext = l.lexCheckbox
+}
+
+// checkboxLength returns the length of the checkbox at the start of the input,
+// `[ ]` or `[x]` in either case, or 0 if there isn't one. like a list marker,
+// the checkbox must be followed by whitespace or the end of the input
+func checkboxLength(input string) int {
+	if len(input) < len(checkboxChecked) {
+		return 0
+	}
+	box := strings.ToLower(input[:len(checkboxChecked)])
+	if box != checkboxUnchecked && box != checkboxChecked {
+		return 0
+	}
+	rest := input[len(box):]
+	if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+		return 0
+	}
+	return len(box)
+}
+
+// lexCheckbox lexes the checkbox beginning the text of a list item, if it has
+// one, and continues with `lexText` from the whitespace after it. anywhere else
+// the squares are read as text or a tag as usual
+func (l *lexer) lexCheckbox() {
+	l.token = token{}
+	l.lexNext = l.lexText
+	rest := strings.TrimLeft(l.input[l.pos:], " \t")
+	length := checkboxLength(rest)
+	if length == 0 {
+		return
+	}
+	l.nextN(len(l.input[l.pos:]) - len(rest))
+	l.token = l.mkToken(typeCheckbox, strings.ToLower(rest[:length]))
+	l.nextN(length)
+}
@@ -1,6 +1,8 @@
 package runic
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -157,6 +159,70 @@ var lexTests = []lexTest{
 			{Typ: typeEOF, Val: "", Line: 4, Pos: 53},
 		},
 	},
+	{
+		"plain text w/ CRLF newline",
+		"The quick brown fox\r\njumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox jumps over the lazy dog", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 44},
+		},
+	},
+	{
+		"plain text w/ multiple CRLF newlines",
+		"The quick brown fox\r\n\r\njumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 21},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 3, Pos: 23},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 46},
+		},
+	},
+	{
+		"plain text w/ multiple CRLF newlines v2",
+		"The quick brown fox\r\n\r\n\r\n\r\n\r\njumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 5, Pos: 27},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 6, Pos: 29},
+			{Typ: typeEOF, Val: "", Line: 6, Pos: 52},
+		},
+	},
+	{
+		"plain text w/ multiple CRLF newlines v3",
+		"The quick brown fox \r\n \r\n \r\n jumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 3, Pos: 28},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 4, Pos: 29},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 52},
+		},
+	},
+	{
+		"plain text w/ multiple CR newlines",
+		"The quick brown fox\r\rjumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 20},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 3, Pos: 21},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 44},
+		},
+	},
+	{
+		"list and escaped newline w/ CRLF newlines",
+		". Heading\r\n- Item one\r\n- Item two\r\n\r\nThe quick\\\r\nfox",
+		[]token{
+			{Typ: typeHeading, Val: ".", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Heading", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 9},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 11},
+			{Typ: typeText, Val: "Item one", Line: 2, Pos: 13},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 23},
+			{Typ: typeText, Val: "Item two", Line: 3, Pos: 25},
+			{Typ: typeTerminator, Val: "\n", Line: 4, Pos: 35},
+			{Typ: typeText, Val: "The quickfox", Line: 5, Pos: 37},
+			{Typ: typeEOF, Val: "", Line: 6, Pos: 52},
+		},
+	},
 	{
 		"plain text containing backslash",
 		"The quick brown fox jumps\\\\over the lazy dog",
@@ -239,8 +305,8 @@ var lexTests = []lexTest{
 		"heading w/ excessive characters",
 		":::. This is a heading",
 		[]token{
-			{Typ: typeHeading, Val: ":::", Line: 1, Pos: 0},
-			{Typ: typeText, Val: ". This is a heading", Line: 1, Pos: 3},
+			{Typ: typeHeading, Val: ":::.", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "This is a heading", Line: 1, Pos: 5},
 			{Typ: typeEOF, Val: "", Line: 1, Pos: 22},
 		},
 	},
@@ -248,11 +314,20 @@ var lexTests = []lexTest{
 		"heading w/ excessive characters v2",
 		":::: This is a heading",
 		[]token{
-			{Typ: typeHeading, Val: ":::", Line: 1, Pos: 0},
-			{Typ: typeText, Val: ": This is a heading", Line: 1, Pos: 3},
+			{Typ: typeHeading, Val: "::::", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "This is a heading", Line: 1, Pos: 5},
 			{Typ: typeEOF, Val: "", Line: 1, Pos: 22},
 		},
 	},
+	{
+		"heading w/ excessive characters v3",
+		".:.:. This is a heading",
+		[]token{
+			{Typ: typeHeading, Val: ".:.:.", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "This is a heading", Line: 1, Pos: 6},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 23},
+		},
+	},
 	{
 		"heading one w/ paragraph underneath",
 		". This is a level one heading\nThe quick brown fox jumps over the lazy dog",
@@ -343,6 +418,62 @@ var lexTests = []lexTest{
 			{Typ: typeEOF, Val: "", Line: 1, Pos: 8},
 		},
 	},
+	{
+		"strike tag without closing square",
+		"strike[The",
+		[]token{
+			{Typ: typeTag, Val: "strike", Line: 1, Pos: 0},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 6},
+			{Typ: typeText, Val: "The", Line: 1, Pos: 7},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"link",
+		"See link(https://example.com/?a=1&b=2)[click here] now",
+		[]token{
+			{Typ: typeText, Val: "See", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "link", Line: 1, Pos: 4},
+			{Typ: typeTagArg, Val: "https://example.com/?a=1&b=2", Line: 1, Pos: 8},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 38},
+			{Typ: typeText, Val: "click here", Line: 1, Pos: 39},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 49},
+			{Typ: typeText, Val: "now", Line: 1, Pos: 51},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 54},
+		},
+	},
+	{
+		"image",
+		"A image(https://x/y.png?a=1&b=2)[a cat]",
+		[]token{
+			{Typ: typeText, Val: "A", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "image", Line: 1, Pos: 2},
+			{Typ: typeTagArg, Val: "https://x/y.png?a=1&b=2", Line: 1, Pos: 7},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 32},
+			{Typ: typeText, Val: "a cat", Line: 1, Pos: 33},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 38},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 39},
+		},
+	},
+	{
+		"link w/ parens not followed by opening square",
+		"a link(b) c",
+		[]token{
+			{Typ: typeText, Val: "a link(b) c", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 11},
+		},
+	},
+	{
+		"rich text w/ escaped squares",
+		"bold[a \\[ b \\] c]",
+		[]token{
+			{Typ: typeTag, Val: "bold", Line: 1, Pos: 0},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 4},
+			{Typ: typeText, Val: "a [ b ] c", Line: 1, Pos: 5},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 16},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 17},
+		},
+	},
 	{
 		"rich text with a closing square",
 		"The quick brown fox ] jumps over the lazy dog",
@@ -564,9 +695,9 @@ var lexTests = []lexTest{
 	       - Item two
 	  `,
 		[]token{
-			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 9, indent: 8},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 9, indent: 11},
 			{Typ: typeText, Val: "Item one", Line: 2, Pos: 11},
-			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 28, indent: 8},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 28, indent: 11},
 			{Typ: typeText, Val: "Item two", Line: 3, Pos: 30},
 			{Typ: typeTerminator, Val: "\n", Line: 3, Pos: 41},
 			{Typ: typeEOF, Val: "", Line: 4, Pos: 42},
@@ -588,6 +719,497 @@ var lexTests = []lexTest{
 			{Typ: typeEOF, Val: "", Line: 2, Pos: 35},
 		},
 	},
+	{
+		"list w/ blank line between items",
+		"- Item one\n\n- Item two\n- Item three",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 12, indent: 0, blankLine: true},
+			{Typ: typeText, Val: "Item two", Line: 3, Pos: 14},
+			{Typ: typeBulletpoint, Val: "-", Line: 4, Pos: 23, indent: 0},
+			{Typ: typeText, Val: "Item three", Line: 4, Pos: 25},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 35},
+		},
+	},
+	{
+		"plain text w/ escaped newline",
+		"The quick brown fox jum\\\nps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox jumps over the lazy dog", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 45},
+		},
+	},
+	{
+		"plain text w/ escaped newline and indent",
+		"The quick brown fox jum\\\n    ps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox jumps over the lazy dog", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 49},
+		},
+	},
+	{
+		"plain text w/ escaped newline before blank line",
+		"The quick brown fox\\\n\njumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 21},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 3, Pos: 22},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 45},
+		},
+	},
+	{
+		"tag name split by escaped newline",
+		"The bo\\\nld[fox]",
+		[]token{
+			{Typ: typeText, Val: "The bo", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "ld", Line: 2, Pos: 8},
+			{Typ: typeOpeningSquare, Val: "[", Line: 2, Pos: 10},
+			{Typ: typeText, Val: "fox", Line: 2, Pos: 11},
+			{Typ: typeClosingSquare, Val: "]", Line: 2, Pos: 14},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 15},
+		},
+	},
+	{
+		"tag name before escaped newline",
+		"The bold\\\n[fox]",
+		[]token{
+			{Typ: typeText, Val: "The bold", Line: 1, Pos: 0},
+			{Typ: typeOpeningSquare, Val: "[", Line: 2, Pos: 10},
+			{Typ: typeText, Val: "fox", Line: 2, Pos: 11},
+			{Typ: typeClosingSquare, Val: "]", Line: 2, Pos: 14},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 15},
+		},
+	},
+	{
+		"heading w/ escaped newline",
+		". This is a level one hea\\\nding\nThe quick brown fox",
+		[]token{
+			{Typ: typeHeading, Val: ".", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "This is a level one heading", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 31},
+			{Typ: typeText, Val: "The quick brown fox", Line: 3, Pos: 32},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 51},
+		},
+	},
+	{
+		"plain text w/ line break",
+		"The quick brown fox \\\\\n  jumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeLineBreak, Val: "\\\\", Line: 1, Pos: 20},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 2, Pos: 25},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 48},
+		},
+	},
+	{
+		"plain text w/ line break before blank line",
+		"The quick brown fox\\\\\n\njumps over the lazy dog",
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox\\", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 22},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 3, Pos: 23},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 46},
+		},
+	},
+	{
+		"plain text w/ escaped paragraph start and line break",
+		"\\. The quick\\\\\r\nbrown fox",
+		[]token{
+			{Typ: typeText, Val: ". The quick", Line: 1, Pos: 0},
+			{Typ: typeLineBreak, Val: "\\\\", Line: 1, Pos: 12},
+			{Typ: typeText, Val: "brown fox", Line: 2, Pos: 16},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 25},
+		},
+	},
+	{
+		"tags w/ mixed case names",
+		"Link(https://example.com)[docs] IF[draft]{x}",
+		[]token{
+			{Typ: typeTag, Val: "Link", Line: 1, Pos: 0},
+			{Typ: typeTagArg, Val: "https://example.com", Line: 1, Pos: 4},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 25},
+			{Typ: typeText, Val: "docs", Line: 1, Pos: 26},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 30},
+			{Typ: typeTag, Val: "IF", Line: 1, Pos: 32},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 34},
+			{Typ: typeText, Val: "draft", Line: 1, Pos: 35},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 40},
+			{Typ: typeOpeningCurly, Val: "{", Line: 1, Pos: 41},
+			{Typ: typeText, Val: "x", Line: 1, Pos: 42},
+			{Typ: typeClosingCurly, Val: "}", Line: 1, Pos: 43},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 44},
+		},
+	},
+	{
+		"list ending newline",
+		"- Item one\n",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 10},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 11},
+		},
+	},
+	{
+		"list ending blank line",
+		"- Item one\n\n",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 11},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 12},
+		},
+	},
+	{
+		"list ending trailing space",
+		"- Item one   ",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 13},
+		},
+	},
+	{
+		"list ending mixed whitespace",
+		"- Item one \n  \n\t",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 15},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 16},
+		},
+	},
+	{
+		"list ending tab indented blank line",
+		"- Item one\n- Item two\n\t\n",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 11, indent: 0},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 13},
+			{Typ: typeTerminator, Val: "\n", Line: 3, Pos: 23},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 24},
+		},
+	},
+	{
+		"list ending empty bulletpoint",
+		"- Item one\n-",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 11, indent: 0},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 12},
+		},
+	},
+	{
+		"condition",
+		"The quick if[draft]{brown bold[fox]} jumps",
+		[]token{
+			{Typ: typeText, Val: "The quick", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "if", Line: 1, Pos: 10},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 12},
+			{Typ: typeText, Val: "draft", Line: 1, Pos: 13},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 18},
+			{Typ: typeOpeningCurly, Val: "{", Line: 1, Pos: 19},
+			{Typ: typeText, Val: "brown", Line: 1, Pos: 20},
+			{Typ: typeTag, Val: "bold", Line: 1, Pos: 26},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 30},
+			{Typ: typeText, Val: "fox", Line: 1, Pos: 31},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 34},
+			{Typ: typeClosingCurly, Val: "}", Line: 1, Pos: 35},
+			{Typ: typeText, Val: "jumps", Line: 1, Pos: 37},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 42},
+		},
+	},
+	{
+		"curly brackets inside plain text",
+		"The quick bold[brown]{fox} jumps }",
+		[]token{
+			{Typ: typeText, Val: "The quick", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "bold", Line: 1, Pos: 10},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 14},
+			{Typ: typeText, Val: "brown", Line: 1, Pos: 15},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 20},
+			{Typ: typeText, Val: "{fox} jumps }", Line: 1, Pos: 21},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 34},
+		},
+	},
+	{
+		"heading w/ only whitespace",
+		". ",
+		[]token{
+			{Typ: typeHeading, Val: ".", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 2},
+		},
+	},
+	{
+		"heading w/ only newline",
+		".\n",
+		[]token{
+			{Typ: typeHeading, Val: ".", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 1},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 2},
+		},
+	},
+	{
+		"closing square before opening square",
+		"A][",
+		[]token{
+			{Typ: typeText, Val: "A", Line: 1, Pos: 0},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 1},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 2},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 3},
+		},
+	},
+	{
+		"multibyte tag",
+		"ñandú[x]",
+		[]token{
+			{Typ: typeTag, Val: "ñandú", Line: 1, Pos: 0},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 7},
+			{Typ: typeText, Val: "x", Line: 1, Pos: 8},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 9},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"tag after punctuation",
+		"a-b[c]",
+		[]token{
+			{Typ: typeText, Val: "a-", Line: 1, Pos: 0},
+			{Typ: typeTag, Val: "b", Line: 1, Pos: 2},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 3},
+			{Typ: typeText, Val: "c", Line: 1, Pos: 4},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 5},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 6},
+		},
+	},
+	{
+		"list starting input",
+		"- Item one",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"list starting input w/ nested item",
+		"- Item one\n  - Item two",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 13, indent: 2},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 15},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 23},
+		},
+	},
+	{
+		"list starting input w/ rich text",
+		"- bold[Item one]",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeTag, Val: "bold", Line: 1, Pos: 2},
+			{Typ: typeOpeningSquare, Val: "[", Line: 1, Pos: 6},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 7},
+			{Typ: typeClosingSquare, Val: "]", Line: 1, Pos: 15},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 16},
+		},
+	},
+	{
+		"bulletpoint only",
+		"-",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 1},
+		},
+	},
+	{
+		"list w/ mixed tab and space indents",
+		"\t- Item one\n    - Item two\n  \t- Item three",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 1, indent: 4},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 3},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 16, indent: 4},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 18},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 30, indent: 4},
+			{Typ: typeText, Val: "Item three", Line: 3, Pos: 32},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 42},
+		},
+	},
+	{
+		"ordered list",
+		"1. Item one\n10. Item two",
+		[]token{
+			{Typ: typeNumberpoint, Val: "1.", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 3},
+			{Typ: typeNumberpoint, Val: "10.", Line: 2, Pos: 12},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 16},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 24},
+		},
+	},
+	{
+		"ordered list nested in unordered list",
+		"- Item one\n  1. Item two",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeNumberpoint, Val: "1.", Line: 2, Pos: 13, indent: 2},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 16},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 24},
+		},
+	},
+	{
+		"task items",
+		"- [ ] Item one\n  1. [X] Item two\n- Item [x]\n-[x] three",
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0},
+			{Typ: typeCheckbox, Val: "[ ]", Line: 1, Pos: 2},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 6},
+			{Typ: typeNumberpoint, Val: "1.", Line: 2, Pos: 17, indent: 2},
+			{Typ: typeCheckbox, Val: "[x]", Line: 2, Pos: 20},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 24},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 33},
+			{Typ: typeText, Val: "Item", Line: 3, Pos: 35},
+			{Typ: typeOpeningSquare, Val: "[", Line: 3, Pos: 40},
+			{Typ: typeText, Val: "x", Line: 3, Pos: 41},
+			{Typ: typeClosingSquare, Val: "]", Line: 3, Pos: 42},
+			{Typ: typeBulletpoint, Val: "-", Line: 4, Pos: 44},
+			{Typ: typeCheckbox, Val: "[x]", Line: 4, Pos: 45},
+			{Typ: typeText, Val: "three", Line: 4, Pos: 49},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 54},
+		},
+	},
+	{
+		"frontmatter",
+		"---\ntitle: Runic\r\n---\n\nText",
+		[]token{
+			{Typ: typeFrontmatter, Val: "---\ntitle: Runic\r\n---", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Text", Line: 5, Pos: 23},
+			{Typ: typeEOF, Val: "", Line: 5, Pos: 27},
+		},
+	},
+	{
+		"comment between paragraphs",
+		"Para one\n\n// a note\r\n\nPara two",
+		[]token{
+			{Typ: typeText, Val: "Para one", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 9},
+			{Typ: typeComment, Val: "// a note", Line: 3, Pos: 10},
+			{Typ: typeText, Val: "Para two", Line: 5, Pos: 22},
+			{Typ: typeEOF, Val: "", Line: 5, Pos: 30},
+		},
+	},
+	{
+		"comments around list",
+		"  //\n- Item\n// c",
+		[]token{
+			{Typ: typeComment, Val: "//", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 5},
+			{Typ: typeText, Val: "Item", Line: 2, Pos: 7},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 11},
+			{Typ: typeComment, Val: "// c", Line: 3, Pos: 12},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 16},
+		},
+	},
+	{
+		"comment mid-paragraph",
+		"Para one\n  // a note\nPara two",
+		[]token{
+			{Typ: typeText, Val: "Para one", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 10},
+			{Typ: typeComment, Val: "// a note", Line: 2, Pos: 11},
+			{Typ: typeText, Val: "Para two", Line: 3, Pos: 21},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 29},
+		},
+	},
+	{
+		"comment marker inside paragraph line",
+		"Para one // not a comment",
+		[]token{
+			{Typ: typeText, Val: "Para one // not a comment", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 25},
+		},
+	},
+	{
+		"number without ordered list marker",
+		"3.14 is pi",
+		[]token{
+			{Typ: typeText, Val: "3.14 is pi", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"escaped closing square at EOF",
+		`The quick brown fox\]`,
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox]", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 21},
+		},
+	},
+	{
+		"quote over two lines",
+		"> Line one\n  > Line two",
+		[]token{
+			{Typ: typeQuote, Val: ">", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Line one", Line: 1, Pos: 2},
+			{Typ: typeQuote, Val: ">", Line: 2, Pos: 13},
+			{Typ: typeText, Val: "Line two", Line: 2, Pos: 15},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 23},
+		},
+	},
+	{
+		"quote followed by text",
+		"> Quote\nText",
+		[]token{
+			{Typ: typeQuote, Val: ">", Line: 1, Pos: 0},
+			{Typ: typeText, Val: "Quote", Line: 1, Pos: 2},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 7},
+			{Typ: typeText, Val: "Text", Line: 2, Pos: 8},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 12},
+		},
+	},
+	{
+		"greater than inside text",
+		"5 > 4",
+		[]token{
+			{Typ: typeText, Val: "5 > 4", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 5},
+		},
+	},
+	{
+		"rule between paragraphs",
+		"Roses\n---\nViolets",
+		[]token{
+			{Typ: typeText, Val: "Roses", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 1, Pos: 5},
+			{Typ: typeRule, Val: "---", Line: 2, Pos: 6},
+			{Typ: typeText, Val: "Violets", Line: 3, Pos: 10},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 17},
+		},
+	},
+	{
+		"rule of equals signs after blank line",
+		"Roses\n\n=====\n\nViolets",
+		[]token{
+			{Typ: typeText, Val: "Roses", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 6},
+			{Typ: typeRule, Val: "=====", Line: 3, Pos: 7},
+			{Typ: typeText, Val: "Violets", Line: 5, Pos: 14},
+			{Typ: typeEOF, Val: "", Line: 5, Pos: 21},
+		},
+	},
+	{
+		"rule w/ trailing space and two hyphens",
+		"--- \n-- a",
+		[]token{
+			{Typ: typeRule, Val: "---", Line: 1, Pos: 0},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 5},
+			{Typ: typeText, Val: "- a", Line: 2, Pos: 6},
+			{Typ: typeEOF, Val: "", Line: 2, Pos: 9},
+		},
+	},
 }
 
 func tokensAreEqual(lexedTokens, expectedTokens []token) bool {
@@ -611,6 +1233,9 @@ func tokensAreEqual(lexedTokens, expectedTokens []token) bool {
 		if lexedTokens[i].indent != expectedTokens[i].indent {
 			return false
 		}
+		if lexedTokens[i].blankLine != expectedTokens[i].blankLine {
+			return false
+		}
 	}
 
 	return true
@@ -634,3 +1259,303 @@ func TestLex(t *testing.T) {
 		t.Log(test.name, "OK")
 	}
 }
+
+type lexOptionTest struct {
+	name           string
+	input          string
+	opts           []Option
+	expectedTokens []token
+}
+
+var lexOptionTests = []lexOptionTest{
+	{
+		"hard line breaks",
+		"Roses are red\nViolets are  \n  blue\\\nbells\n",
+		[]Option{WithHardLineBreaks()},
+		[]token{
+			{Typ: typeText, Val: "Roses are red", Line: 1, Pos: 0},
+			{Typ: typeLineBreak, Val: "\n", Line: 1, Pos: 13},
+			{Typ: typeText, Val: "Violets are", Line: 2, Pos: 14},
+			{Typ: typeLineBreak, Val: "\n", Line: 2, Pos: 29},
+			{Typ: typeText, Val: "bluebells", Line: 3, Pos: 30},
+			{Typ: typeEOF, Val: "", Line: 5, Pos: 42},
+		},
+	},
+	{
+		"paragraph merging w/ single blank line",
+		"The quick brown fox\n\njumps over the lazy dog",
+		[]Option{WithParagraphMerging()},
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox jumps over the lazy dog", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 44},
+		},
+	},
+	{
+		"paragraph merging w/ two blank lines",
+		"The quick brown fox\n\n\njumps over the lazy dog",
+		[]Option{WithParagraphMerging()},
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 3, Pos: 21},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 4, Pos: 22},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 45},
+		},
+	},
+	{
+		"paragraph merging w/ two blank lines containing spaces",
+		"The quick brown fox\n    \n    \njumps over the lazy dog",
+		[]Option{WithParagraphMerging()},
+		[]token{
+			{Typ: typeText, Val: "The quick brown fox", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 3, Pos: 29},
+			{Typ: typeText, Val: "jumps over the lazy dog", Line: 4, Pos: 30},
+			{Typ: typeEOF, Val: "", Line: 4, Pos: 53},
+		},
+	},
+	{
+		"preserve empty paragraphs after empty heading",
+		".\n\nThe quick brown fox",
+		[]Option{WithPreserveEmptyParagraphs()},
+		[]token{
+			{Typ: typeHeading, Val: ".", Line: 1, Pos: 0},
+			{Typ: typeTerminator, Val: "\n", Line: 2, Pos: 2},
+			{Typ: typeText, Val: "The quick brown fox", Line: 3, Pos: 3},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 22},
+		},
+	},
+	{
+		"strikethrough shorthand",
+		"The quick ~~brown fox~~ jumps",
+		[]Option{WithStrikethroughShorthand()},
+		[]token{
+			{Typ: typeText, Val: "The quick", Line: 1, Pos: 0},
+			{Typ: typeStrike, Val: "~~", Line: 1, Pos: 10},
+			{Typ: typeText, Val: "brown fox", Line: 1, Pos: 12},
+			{Typ: typeStrike, Val: "~~", Line: 1, Pos: 21},
+			{Typ: typeText, Val: "jumps", Line: 1, Pos: 24},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 29},
+		},
+	},
+	{
+		"strikethrough shorthand w/ literal tildes",
+		"The quick ~ brown \\~~fox",
+		[]Option{WithStrikethroughShorthand()},
+		[]token{
+			{Typ: typeText, Val: "The quick ~ brown ~~fox", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 24},
+		},
+	},
+	{
+		"strikethrough shorthand disabled",
+		"The quick ~~brown fox~~ jumps",
+		nil,
+		[]token{
+			{Typ: typeText, Val: "The quick ~~brown fox~~ jumps", Line: 1, Pos: 0},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 29},
+		},
+	},
+	{
+		"list w/ mixed tab and space indents and tab size",
+		"- Item one\n\t- Item two\n  \t- Item three",
+		[]Option{WithTabSize(8)},
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 0, indent: 0},
+			{Typ: typeText, Val: "Item one", Line: 1, Pos: 2},
+			{Typ: typeBulletpoint, Val: "-", Line: 2, Pos: 12, indent: 8},
+			{Typ: typeText, Val: "Item two", Line: 2, Pos: 14},
+			{Typ: typeBulletpoint, Val: "-", Line: 3, Pos: 26, indent: 8},
+			{Typ: typeText, Val: "Item three", Line: 3, Pos: 28},
+			{Typ: typeEOF, Val: "", Line: 3, Pos: 38},
+		},
+	},
+	{
+		"tab indent w/ zero tab size",
+		"\t- a",
+		[]Option{WithTabSize(0)},
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 1, indent: 4},
+			{Typ: typeText, Val: "a", Line: 1, Pos: 3},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 4},
+		},
+	},
+	{
+		"tab indent w/ negative tab size",
+		"\t- a",
+		[]Option{WithTabSize(-2)},
+		[]token{
+			{Typ: typeBulletpoint, Val: "-", Line: 1, Pos: 1, indent: 4},
+			{Typ: typeText, Val: "a", Line: 1, Pos: 3},
+			{Typ: typeEOF, Val: "", Line: 1, Pos: 4},
+		},
+	},
+}
+
+func TestTokenTypeString(t *testing.T) {
+	names := map[string]tokenType{}
+	for typ := typeNone; typ <= typeRule; typ++ {
+		name := typ.String()
+		if !strings.HasPrefix(name, "type") {
+			t.Errorf("%d ERROR\nexpected: a constant name\nreceived: %s", typ, name)
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("%d ERROR\nexpected: a distinct name\nreceived: %s, the name of %d", typ, name, other)
+		}
+		names[name] = typ
+	}
+
+	if name := (typeRule + 1).String(); name != fmt.Sprintf("tokenType(%d)", typeRule+1) {
+		t.Errorf("unknown type ERROR\nreceived: %s", name)
+	}
+}
+
+type newlineTest struct {
+	name                string
+	opts                []Option
+	expectedTerminators [4]int // terminators lexed for 1, 2, 3 and 4 newlines
+}
+
+var newlineTests = []newlineTest{
+	{
+		"default",
+		nil,
+		[4]int{0, 1, 1, 1},
+	},
+	{
+		"paragraph break of 1",
+		[]Option{WithParagraphBreak(1)},
+		[4]int{1, 1, 1, 1},
+	},
+	{
+		"paragraph merging",
+		[]Option{WithParagraphMerging()},
+		[4]int{0, 0, 1, 1},
+	},
+	{
+		"preserve empty paragraphs",
+		[]Option{WithPreserveEmptyParagraphs()},
+		[4]int{0, 1, 2, 3},
+	},
+	{
+		"preserve empty paragraphs w/ paragraph break of 1",
+		[]Option{WithParagraphBreak(1), WithPreserveEmptyParagraphs()},
+		[4]int{1, 2, 3, 4},
+	},
+	{
+		"preserve empty paragraphs w/ paragraph merging",
+		[]Option{WithParagraphMerging(), WithPreserveEmptyParagraphs()},
+		[4]int{0, 0, 1, 2},
+	},
+}
+
+func TestLexNewlines(t *testing.T) {
+	for _, test := range newlineTests {
+		for _, lineEnding := range []string{"\n", "\r\n", "\r"} {
+			for i, expectedTerminators := range test.expectedTerminators {
+				input := "The quick brown fox" + strings.Repeat(lineEnding, i+1) + "jumps over the lazy dog"
+				lexedTokens := collectTokens(input, test.opts...)
+
+				terminators := 0
+				for _, token := range lexedTokens {
+					if token.Typ == typeTerminator {
+						terminators++
+					}
+				}
+				// lines which are joined become a single text token
+				expectedTokens := 3 + terminators
+				if terminators == 0 {
+					expectedTokens = 2
+				}
+				if terminators != expectedTerminators || len(lexedTokens) != expectedTokens {
+					t.Errorf("%s w/ %d %q newlines ERROR\nexpected: %d terminators\nreceived: %s", test.name, i+1, lineEnding, expectedTerminators, stringifyTokens(lexedTokens))
+				}
+			}
+		}
+	}
+}
+
+func TestLexWithOptions(t *testing.T) {
+	for _, test := range lexOptionTests {
+		lexedTokens := collectTokens(test.input, test.opts...)
+		if !tokensAreEqual(lexedTokens, test.expectedTokens) {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, stringifyTokens(test.expectedTokens), stringifyTokens(lexedTokens))
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+type indentTest struct {
+	name          string
+	opts          []Option
+	tabIndented   string
+	spaceIndented string
+}
+
+var indentTests = []indentTest{
+	{
+		"nested list",
+		nil,
+		"- Item one\n\t- Item two\n\t\t- Item three\n- Item four",
+		"- Item one\n    - Item two\n        - Item three\n- Item four",
+	},
+	{
+		"nested ordered list",
+		nil,
+		"1. Item one\n\t1. Item two\n\t2. Item three\n2. Item four",
+		"1. Item one\n    1. Item two\n    2. Item three\n2. Item four",
+	},
+	{
+		"nested list w/ tab size",
+		[]Option{WithTabSize(2)},
+		"- Item one\n\t- Item two\n\t\t- Item three",
+		"- Item one\n  - Item two\n    - Item three",
+	},
+}
+
+func TestLexTabIndents(t *testing.T) {
+	for _, test := range indentTests {
+		tabTokens := collectTokens(test.tabIndented, test.opts...)
+		spaceTokens := collectTokens(test.spaceIndented, test.opts...)
+		if len(tabTokens) != len(spaceTokens) {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, stringifyTokens(spaceTokens), stringifyTokens(tabTokens))
+			continue
+		}
+		for i := range tabTokens {
+			if tabTokens[i].Typ != spaceTokens[i].Typ || tabTokens[i].Val != spaceTokens[i].Val || tabTokens[i].indent != spaceTokens[i].indent {
+				t.Errorf("%s ERROR\nexpected: %s (indent %d)\nreceived: %s (indent %d)", test.name, spaceTokens[i].String(), spaceTokens[i].indent, tabTokens[i].String(), tabTokens[i].indent)
+			}
+		}
+	}
+}
+
+// BenchmarkLexBlankLines lexes documents of increasing size made of short
+// paragraphs, so whitespace is collapsed every few bytes. the time per byte
+// should stay the same as the size grows
+func BenchmarkLexBlankLines(b *testing.B) {
+	paragraph := "The quick bold[brown fox] jumps over the lazy dog\n\n  \n"
+	for _, size := range []int{64 << 10, 256 << 10, 1 << 20} {
+		input := strings.Repeat(paragraph, size/len(paragraph))
+		b.Run(strconv.Itoa(size>>10)+"KB", func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for range b.N {
+				lexer := lex(input, newConfig())
+				for lexer.nextToken() {
+				}
+			}
+		})
+	}
+}
+
+func FuzzLex(f *testing.F) {
+	for _, test := range lexTests {
+		f.Add(test.input)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens := collectTokens(input, WithStrikethroughShorthand())
+		if len(tokens) == 0 || tokens[len(tokens)-1].Typ != typeEOF {
+			t.Errorf("%q ERROR\nlexer did not finish with typeEOF: %s", input, stringifyTokens(tokens))
+		}
+		New().Html(input)
+		New().HighlightText(input)
+	})
+}
@@ -0,0 +1,30 @@
+package runic
+
+import "strings"
+
+// Metadata parses the input like `Parse`, and also returns the key/value pairs
+// of its frontmatter, a block at the very start of the input between two `---`
+// lines, e.g. `title: The quick fox`. keys and values are trimmed, a key given
+// more than once takes its last value, and lines without a colon are ignored.
+// the frontmatter is left out of the tree, and the map is empty when the input
+// doesn't begin with any
+func (p *parser) Metadata(input string) (map[string]string, *Node) {
+	tree := p.Parse(input)
+	return p.metadata, tree
+}
+
+// parseFrontmatter reads each `key: value` line between the delimiters of the
+// current frontmatter token into `p.metadata`
+func (p *parser) parseFrontmatter() {
+	lines := strings.FieldsFunc(p.lexer.token.Val, func(r rune) bool {
+		return strings.ContainsRune(lineEndings, r)
+	})
+	// the first and last lines are the delimiters
+	for _, line := range lines[1 : len(lines)-1] {
+		key, value, ok := strings.Cut(line, string(charColon))
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			continue
+		}
+		p.metadata[key] = strings.TrimSpace(value)
+	}
+}
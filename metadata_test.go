@@ -0,0 +1,68 @@
+package runic
+
+import (
+	"maps"
+	"testing"
+)
+
+type metadataTest struct {
+	name             string
+	input            string
+	expectedMetadata map[string]string
+	expectedHtml     string
+}
+
+var metadataTests = []metadataTest{
+	{
+		"title and date",
+		"---\ntitle:  The quick fox \ndate: 2024-05-01\n---\n. Heading\n\nThe bold[quick] fox",
+		map[string]string{"title": "The quick fox", "date": "2024-05-01"},
+		"<h1>Heading</h1><p>The <b>quick</b> fox</p>",
+	},
+	{
+		"no frontmatter",
+		"The quick fox\n\ntitle: jumps",
+		map[string]string{},
+		"<p>The quick fox</p><p>title: jumps</p>",
+	},
+	{
+		"duplicate keys",
+		"---\r\ntitle: One\r\n\r\nnot a pair\r\ntitle: Two: The sequel\r\n---",
+		map[string]string{"title": "Two: The sequel"},
+		"",
+	},
+	{
+		"empty frontmatter",
+		"---\n---\nThe quick fox",
+		map[string]string{},
+		"<p>The quick fox</p>",
+	},
+	{
+		"unclosed frontmatter",
+		"---\ntitle: The quick fox",
+		map[string]string{},
+		"<hr><p>title: The quick fox</p>",
+	},
+	{
+		"frontmatter not at start of input",
+		"The quick fox\n\n---\ntitle: jumps\n---",
+		map[string]string{},
+		"<p>The quick fox</p><hr><p>title: jumps</p><hr>",
+	},
+}
+
+func TestMetadata(t *testing.T) {
+	for _, test := range metadataTests {
+		testParser := New()
+		metadata, tree := testParser.Metadata(test.input)
+		if !maps.Equal(metadata, test.expectedMetadata) {
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, test.expectedMetadata, metadata)
+			continue
+		}
+		if htmlString := renderHTML(tree, testParser.config); htmlString != test.expectedHtml {
+			t.Errorf("%s ERROR\nexpected: %s\nreceived: %s", test.name, test.expectedHtml, htmlString)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
@@ -1,14 +1,26 @@
 package runic
 
 import (
+	"encoding/json"
 	"slices"
+	"strings"
 )
 
 type Node struct {
 	Typ      string  `json:"type"`
 	Val      string  `json:"value,omitempty"`
 	Children []*Node `json:"children,omitempty"`
+	Depth    int     `json:"depth,omitempty"`   // nesting level of a list, 0 at the top level
+	Loose    bool    `json:"loose,omitempty"`   // a blank line separates the items of a list
+	Joined   bool    `json:"joined,omitempty"`  // no whitespace separates the node from the one before it
+	Task     bool    `json:"task,omitempty"`    // the list item begins with a checkbox
+	Checked  bool    `json:"checked,omitempty"` // the checkbox of a task list item is ticked
+	Number   int     `json:"number,omitempty"`  // number written on an ordered list item, see `WithListStartFromSource`
+	Start    int     `json:"start,omitempty"`   // number of the first item of an ordered list resumed by `WithOrderedListContinuation`
+	Line     int     `json:"line,omitempty"`    // line in the input where the node begins
+	Pos      int     `json:"pos,omitempty"`     // byte offset in the input where the node begins
 	parent   *Node
+	end      int // byte offset in the input just past the node
 }
 
 const INDENT_WIDTH = 2
@@ -26,10 +38,29 @@ const (
 	nodeText         = "Text"
 	nodeBoldTag      = "BoldTag"
 	nodeItalicTag    = "ItalicTag"
+	nodeUnderlineTag = "UnderlineTag"
+	nodeKbdTag       = "KbdTag"
+	nodeMarkTag      = "MarkTag"
+	nodeCustomTag    = "CustomTag"
+	nodeCodeTag      = "CodeTag"
+	nodeLinkTag      = "LinkTag"
+	nodeImageTag     = "ImageTag"
+	nodeColorTag     = "ColorTag"
 	nodeList         = "List"
+	nodeOrderedList  = "OrderedList"
+	nodeBlockquote   = "Blockquote"
 	nodeListItem     = "ListItem"
+	nodeConditionTag = "ConditionTag"
+	nodeStrikeTag    = "StrikeTag"
+	nodeVariableTag  = "VariableTag"
+	nodeLineBreak    = "LineBreak"
+	nodeRule         = "Rule"
 )
 
+// the markers of each heading level. each level adds a dot or turns the last
+// dot into a colon, so a valid marker is a run of colons, optionally followed by
+// a single dot. markers deeper than `nodeHeadingSixValue` are read as it, since
+// HTML has no deeper heading, see `headingValue`
 const (
 	nodeHeadingOneValue   = "."
 	nodeHeadingTwoValue   = ":"
@@ -40,14 +71,186 @@ const (
 )
 
 var (
-	errInvalidTag     = "Invalid tag name"
-	errInvalidHeading = "Invalid heading value"
+	errInvalidTag        = "Invalid tag name"
+	errInvalidHeading    = "Invalid heading value"
+	errUnclosedTag       = "Unclosed tag"
+	errInvalidCondition  = "Invalid condition"
+	errUnclosedCondition = "Unclosed condition"
+	errCutCondition      = "Condition cut short"
+	errInvalidVariable   = "Invalid variable"
+	errUnknownVariable   = "Unknown variable"
+	errEmptyTag          = "Empty tag"
+	errInvalidLink       = "Invalid link"
+	errInvalidImage      = "Invalid image"
+	errInvalidColor      = "Invalid color"
+	errUnknownColor      = "Unknown color"
 )
 
+// errorCodes maps each error message to a stable code for tooling, which stays
+// the same if the wording of the message changes
+var errorCodes = map[string]string{
+	errInvalidTag:        "invalid_tag",
+	errInvalidHeading:    "invalid_heading",
+	errUnclosedTag:       "unclosed_tag",
+	errInvalidCondition:  "invalid_condition",
+	errUnclosedCondition: "unclosed_condition",
+	errCutCondition:      "cut_condition",
+	errInvalidVariable:   "invalid_variable",
+	errUnknownVariable:   "unknown_variable",
+	errEmptyTag:          "empty_tag",
+	errInvalidLink:       "invalid_link",
+	errInvalidImage:      "invalid_image",
+	errInvalidColor:      "invalid_color",
+	errUnknownColor:      "unknown_color",
+}
+
 func isOneOf(nodeType string, nodeTypes ...string) bool {
 	return slices.Contains(nodeTypes, nodeType)
 }
 
+// splitNestedLists splits the children of a list item into its content and the
+// lists nested in it, which always follow the content
+func splitNestedLists(item *Node) (content, lists []*Node) {
+	i := slices.IndexFunc(item.Children, func(n *Node) bool {
+		return isOneOf(n.Typ, nodeList, nodeOrderedList)
+	})
+	if i < 0 {
+		return item.Children, nil
+	}
+	return item.Children[:i], item.Children[i:]
+}
+
 func getListItemDepth(listItem token) int {
 	return listItem.indent / INDENT_WIDTH
 }
+
+// newNode returns a node of the provided type and value, adopting the given
+// children
+func newNode(typ, val string, children ...*Node) *Node {
+	n := &Node{Typ: typ, Val: val}
+	for _, child := range children {
+		n.AppendChild(child)
+	}
+	return n
+}
+
+// NewRoot returns a root node containing the given blocks
+func NewRoot(children ...*Node) *Node {
+	return newNode(nodeRoot, "", children...)
+}
+
+// NewParagraph returns a paragraph node containing the given inline nodes
+func NewParagraph(children ...*Node) *Node {
+	return newNode(nodeParagraph, "", children...)
+}
+
+// NewText returns a text node holding the given string
+func NewText(s string) *Node {
+	return newNode(nodeText, s)
+}
+
+// NewBold returns a bold tag node containing the given inline nodes
+func NewBold(children ...*Node) *Node {
+	return newNode(nodeBoldTag, "", children...)
+}
+
+// NewItalic returns an italic tag node containing the given inline nodes
+func NewItalic(children ...*Node) *Node {
+	return newNode(nodeItalicTag, "", children...)
+}
+
+// AppendChild adds the given node as the last child of n
+func (n *Node) AppendChild(child *Node) {
+	child.parent = n
+	n.Children = append(n.Children, child)
+}
+
+// setPosition places the node at the start of the given token
+func (n *Node) setPosition(t token) {
+	n.Line = t.Line
+	n.Pos = t.Pos
+}
+
+// Parent returns the node n is a child of, or nil for the root of a tree
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Text returns the plain text of n and its descendants, being the values of
+// its text and code nodes separated by a space unless a node is joined to the
+// one before it, and a newline for each line break. markup such as tags, list
+// markers and variables is left out
+func (n *Node) Text() string {
+	s := ""
+	switch n.Typ {
+	case nodeText, nodeCodeTag:
+		s = n.Val
+	case nodeLineBreak:
+		s = string(charNewline)
+	}
+	for _, child := range n.Children {
+		text := child.Text()
+		if text == "" {
+			continue
+		}
+		newline := strings.HasSuffix(s, string(charNewline)) || strings.HasPrefix(text, string(charNewline))
+		if s != "" && !child.Joined && !newline {
+			s += " "
+		}
+		s += text
+	}
+	return s
+}
+
+// NodeFromJSON rebuilds a tree from its JSON, as written by `json.Marshal`,
+// with each node linked to its parent so that `Parent` works
+func NodeFromJSON(data []byte) (*Node, error) {
+	n := &Node{}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, err
+	}
+	linkParents(n)
+	return n, nil
+}
+
+// linkParents sets the parent of each node below n, which JSON leaves out
+func linkParents(n *Node) {
+	for _, child := range n.Children {
+		child.parent = n
+		linkParents(child)
+	}
+}
+
+// Walk calls `fn` for n and each of its descendants in depth-first pre-order,
+// so a node is visited before its children and the children are visited in
+// order. when `fn` returns false the children of that node are skipped
+func Walk(n *Node, fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		Walk(child, fn)
+	}
+}
+
+// SetText replaces the value of a text node. it has no effect on other node
+// types
+func (n *Node) SetText(s string) {
+	if n.Typ != nodeText {
+		return
+	}
+	n.Val = s
+}
+
+// AppendText adds the given string to the end of a text node's value,
+// separated by a space. it has no effect on other node types
+func (n *Node) AppendText(s string) {
+	if n.Typ != nodeText {
+		return
+	}
+	if n.Val == "" {
+		n.Val = s
+		return
+	}
+	n.Val += " " + s
+}
@@ -0,0 +1,200 @@
+package runic
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestNodeBuilder(t *testing.T) {
+	quick := NewText("quick")
+	paragraph := NewParagraph(
+		NewText("The"),
+		NewBold(quick, NewItalic(NewText("brown"))),
+		NewText("fox"),
+	)
+	tree := NewRoot(paragraph)
+
+	quick.SetText("slow")
+	quick.AppendText("and")
+	paragraph.SetText("ignored")
+
+	expectedHtml := "<p>The <b>slow and <em>brown</em></b> fox</p>"
+	if htmlString := renderHTML(tree, newConfig()); htmlString != expectedHtml {
+		t.Errorf("expected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+	if paragraph.Val != "" {
+		t.Errorf("SetText changed a paragraph value to %q", paragraph.Val)
+	}
+	if quick.parent.Typ != nodeBoldTag || paragraph.parent != tree {
+		t.Error("builder did not link parent nodes")
+	}
+}
+
+func TestEmptyListIsDropped(t *testing.T) {
+	tree := NewRoot(
+		NewParagraph(NewText("The quick brown fox")),
+		newNode(nodeList, ""),
+		NewParagraph(NewText("jumps over the lazy dog")),
+	)
+
+	expectedHtml := "<p>The quick brown fox</p><p>jumps over the lazy dog</p>"
+	if htmlString := renderHTML(tree, newConfig()); htmlString != expectedHtml {
+		t.Errorf("expected: %s\nreceived: %s", expectedHtml, htmlString)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		skip          string
+		expectedOrder []string
+	}{
+		{
+			"nested rich text",
+			"The quick bold[brown fox italic[jumps] over the] lazy dog",
+			"",
+			[]string{nodeRoot, nodeParagraph, nodeText, nodeBoldTag, nodeText, nodeItalicTag, nodeText, nodeText, nodeText},
+		},
+		{
+			"nested list",
+			"- Item one\n  - Item two\n- Item three",
+			"",
+			[]string{nodeRoot, nodeList, nodeListItem, nodeText, nodeList, nodeListItem, nodeText, nodeListItem, nodeText},
+		},
+		{
+			"nested list w/ skipped children",
+			"- Item one\n  - Item two\n- Item three",
+			nodeListItem,
+			[]string{nodeRoot, nodeList, nodeListItem, nodeListItem},
+		},
+	}
+
+	for _, test := range tests {
+		order := []string{}
+		Walk(New().Parse(test.input), func(n *Node) bool {
+			order = append(order, n.Typ)
+			return n.Typ != test.skip
+		})
+		if !slices.Equal(order, test.expectedOrder) {
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, test.expectedOrder, order)
+		}
+	}
+}
+
+func TestParent(t *testing.T) {
+	tree := New().Parse("The quick\n\n- Item bold[one]\n  - Item italic[two]")
+	if tree.Parent() != nil {
+		t.Errorf("root ERROR\nexpected: nil\nreceived: %v", tree.Parent())
+	}
+
+	Walk(tree, func(n *Node) bool {
+		for _, child := range n.Children {
+			if child.Parent() != n {
+				t.Errorf("%s ERROR\nexpected parent: %s\nreceived parent: %v", child.Typ, n.Typ, child.Parent())
+			}
+		}
+		return true
+	})
+
+	// walk up from the text of the nested italic tag
+	italicText := tree.Children[1].Children[0].Children[2].Children[0].Children[1].Children[0]
+	path := []string{}
+	for n := italicText; n != nil; n = n.Parent() {
+		path = append(path, n.Typ)
+	}
+	expectedPath := []string{nodeText, nodeItalicTag, nodeListItem, nodeList, nodeListItem, nodeList, nodeRoot}
+	if !slices.Equal(path, expectedPath) {
+		t.Errorf("path ERROR\nexpected: %v\nreceived: %v", expectedPath, path)
+	}
+}
+
+func TestNodeFromJSON(t *testing.T) {
+	testParser := New(WithListLooseDetection())
+	tree := testParser.Parse("The quick\n\n- Item bold[one]\n\n- Item two\n  - Item italic[three]")
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rehydrated, err := NodeFromJSON(treeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Walk(rehydrated, func(n *Node) bool {
+		for _, child := range n.Children {
+			if child.Parent() != n {
+				t.Errorf("%s ERROR\nexpected parent: %s\nreceived parent: %v", child.Typ, n.Typ, child.Parent())
+			}
+		}
+		return true
+	})
+
+	if runic, expected := testParser.ToRunic(rehydrated), testParser.ToRunic(tree); runic != expected {
+		t.Errorf("runic ERROR\nexpected: %q\nreceived: %q", expected, runic)
+	}
+	if html, expected := renderHTML(rehydrated, testParser.config), renderHTML(tree, testParser.config); html != expected {
+		t.Errorf("html ERROR\nexpected: %q\nreceived: %q", expected, html)
+	}
+	// an item reads whether it is loose from its parent list
+	item, expectedItem := rehydrated.Children[1].Children[0], tree.Children[1].Children[0]
+	if html, expected := testParser.NodeHtml(item), testParser.NodeHtml(expectedItem); html != expected {
+		t.Errorf("item html ERROR\nexpected: %q\nreceived: %q", expected, html)
+	}
+
+	if _, err := NodeFromJSON([]byte("{")); err == nil {
+		t.Errorf("invalid JSON ERROR\nexpected an error")
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedText string
+	}{
+		{
+			"nested rich text",
+			"The quick brown fox italic[jumps] over the lazy dog",
+			"The quick brown fox jumps over the lazy dog",
+		},
+		{
+			"heading",
+			". The bold[quick italic[brown]] fox",
+			"The quick brown fox",
+		},
+		{
+			"nested list",
+			"- Item one\n  - Item two\n- Item three",
+			"Item one Item two Item three",
+		},
+		{
+			"joined tags and code",
+			"The quick\\bold[brown]\\italic[fox] ran code[fmt.Println] var[name]",
+			"The quickbrownfox ran fmt.Println",
+		},
+		{
+			"text joined to a tag",
+			"The bold[quick]! fox",
+			"The quick! fox",
+		},
+		{
+			"line break",
+			"The quick\\\\\nbold[brown] fox",
+			"The quick\nbrown fox",
+		},
+	}
+
+	for _, test := range tests {
+		tree := New().Parse(test.input)
+		if text := tree.Text(); text != test.expectedText {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedText, text)
+		}
+	}
+
+	heading := New().Parse(". Title\nThe quick fox").Children[0]
+	if text := heading.Text(); text != "Title" {
+		t.Errorf("heading node ERROR\nexpected: %q\nreceived: %q", "Title", text)
+	}
+}
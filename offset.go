@@ -0,0 +1,31 @@
+package runic
+
+import "unicode/utf8"
+
+// positions reported by the lexer and parser (such as `Diagnostic.Pos`) are
+// byte offsets into the input text. editors which index text differently can
+// convert them with the helpers below
+
+// RuneOffset converts a byte offset in the input text to the number of runes
+// preceding it
+func RuneOffset(input string, byteOffset int) int {
+	return utf8.RuneCountInString(input[:clampOffset(input, byteOffset)])
+}
+
+// UTF16Offset converts a byte offset in the input text to the number of UTF-16
+// code units preceding it, as used by JavaScript strings
+func UTF16Offset(input string, byteOffset int) (offset int) {
+	for _, char := range input[:clampOffset(input, byteOffset)] {
+		// runes outside the basic multilingual plane are stored as a surrogate pair
+		if char > 0xFFFF {
+			offset += 2
+			continue
+		}
+		offset++
+	}
+	return
+}
+
+func clampOffset(input string, byteOffset int) int {
+	return max(0, min(byteOffset, len(input)))
+}
@@ -0,0 +1,51 @@
+package runic
+
+import (
+	"slices"
+	"testing"
+)
+
+type offsetTest struct {
+	name                string
+	input               string
+	expectedByteOffsets []int
+	expectedRuneOffsets []int
+	expectedUTF16       []int
+}
+
+var offsetTests = []offsetTest{
+	{
+		"ascii",
+		"The bold[fox]",
+		[]int{0, 4, 8, 9, 12, 13},
+		[]int{0, 4, 8, 9, 12, 13},
+		[]int{0, 4, 8, 9, 12, 13},
+	},
+	{
+		"multibyte",
+		"Ünïcödé bold[日本] 🦊 fox",
+		[]int{0, 12, 16, 17, 23, 25, 33},
+		[]int{0, 8, 12, 13, 15, 17, 22},
+		[]int{0, 8, 12, 13, 15, 17, 23},
+	},
+}
+
+func TestOffsets(t *testing.T) {
+	for _, test := range offsetTests {
+		var byteOffsets, runeOffsets, utf16Offsets []int
+		for _, token := range collectTokens(test.input) {
+			byteOffsets = append(byteOffsets, token.Pos)
+			runeOffsets = append(runeOffsets, RuneOffset(test.input, token.Pos))
+			utf16Offsets = append(utf16Offsets, UTF16Offset(test.input, token.Pos))
+		}
+		if !slices.Equal(byteOffsets, test.expectedByteOffsets) {
+			t.Errorf("%s ERROR\nexpected byte offsets: %v\nreceived: %v", test.name, test.expectedByteOffsets, byteOffsets)
+		}
+		if !slices.Equal(runeOffsets, test.expectedRuneOffsets) {
+			t.Errorf("%s ERROR\nexpected rune offsets: %v\nreceived: %v", test.name, test.expectedRuneOffsets, runeOffsets)
+		}
+		if !slices.Equal(utf16Offsets, test.expectedUTF16) {
+			t.Errorf("%s ERROR\nexpected UTF-16 offsets: %v\nreceived: %v", test.name, test.expectedUTF16, utf16Offsets)
+		}
+	}
+}
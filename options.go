@@ -0,0 +1,352 @@
+package runic
+
+import "strings"
+
+// config holds the settings shared by the lexer, parser, and renderers
+type config struct {
+	paragraphBreak     int                  // number of newlines required to end a paragraph
+	emptyParagraphs    bool                 // render newlines beyond `paragraphBreak` as empty paragraphs
+	hardLineBreaks     bool                 // read a single newline in a paragraph as a line break
+	listDepth          bool                 // render a `data-depth` attribute on lists
+	looseLists         bool                 // wrap the items of loose lists in paragraphs
+	listNumbers        bool                 // keep the number written on each ordered list item
+	listContinuation   bool                 // resume the numbering of an interrupted ordered list
+	microdata          bool                 // render schema.org microdata attributes
+	tabSize            int                  // width of a tab stop
+	flags              map[string]bool      // flags enabling `if[flag]{...}` content
+	strikeShorthand    bool                 // lex `~~text~~` as a strike tag
+	cacheSize          int                  // number of documents kept by `HtmlCached`
+	stableErrors       bool                 // format error node values as `code|message|detail`
+	variables          map[string]string    // values substituted for `var[name]`
+	emptyTags          EmptyTagMode         // how tags without content are handled
+	maxParagraphLength int                  // paragraphs longer than this are split, 0 for no limit
+	colorClasses       map[string]string    // CSS classes rendered for `color(name)[...]`
+	maxOutputBytes     int                  // rendered HTML stops before exceeding this many bytes, 0 for no limit
+	headingIDs         bool                 // render an `id` slug of the text on headings
+	headingIDPrefix    string               // prefix of the ids rendered by `headingIDs`
+	maxHeadingText     int                  // heading text used for an id is cut to this many characters, 0 for no limit
+	classPrefix        string               // prefix of the classes on `HighlightText` spans
+	semanticTags       bool                 // render bold text as `<strong>` rather than `<b>`
+	customTags         map[string]customTag // tags defined by `WithCustomTag`, by name
+	autoLinks          bool                 // link bare URLs in text
+	indentUnit         string               // indentation of each level of nested list written by `ToRunic`
+	linkTarget         bool                 // open links to other sites in a new tab
+	preserveSpaces     bool                 // keep runs of spaces in text, rendered as `&nbsp;`
+}
+
+// customTag holds the HTML written around the content of a tag defined by
+// `WithCustomTag`
+type customTag struct {
+	htmlOpen  string
+	htmlClose string
+}
+
+// Option configures a parser returned from `New`
+type Option func(*config)
+
+func newConfig(opts ...Option) config {
+	cfg := config{
+		paragraphBreak: 2,
+		tabSize:        4,
+		cacheSize:      128,
+		classPrefix:    "runic__",
+		indentUnit:     strings.Repeat(" ", INDENT_WIDTH),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithParagraphBreak sets the number of consecutive newlines which end a
+// paragraph. fewer newlines join the lines with a space, so 1 makes every line
+// its own paragraph. the default is 2, a single blank line
+func WithParagraphBreak(n int) Option {
+	return func(c *config) {
+		c.paragraphBreak = n
+	}
+}
+
+// WithParagraphMerging joins text separated by a single blank line into the
+// same paragraph. two or more blank lines are required to start a new one. it
+// is the same as `WithParagraphBreak(3)`
+func WithParagraphMerging() Option {
+	return WithParagraphBreak(3)
+}
+
+// WithHardLineBreaks reads a single newline in a paragraph as a line break
+// rather than a space, so line endings are kept in poetry or addresses without
+// writing `\\` at the end of each line. an escaped newline still joins the
+// lines. the newlines ending a paragraph are unchanged, and
+// `WithParagraphBreak(1)` makes each line its own paragraph instead
+func WithHardLineBreaks() Option {
+	return func(c *config) {
+		c.hardLineBreaks = true
+	}
+}
+
+// WithPreserveEmptyParagraphs renders each newline beyond those ending a block
+// as an empty paragraph, so authors can add vertical space. by default any
+// number of blank lines collapses into a single break
+func WithPreserveEmptyParagraphs() Option {
+	return func(c *config) {
+		c.emptyParagraphs = true
+	}
+}
+
+// WithPreserveSpaces keeps each run of spaces typed within text, rendering runs
+// of two or more as `&nbsp;` the way `HighlightText` does, so `a     b` keeps
+// its gap in `Html`. by default any run of whitespace collapses into a single
+// space
+func WithPreserveSpaces() Option {
+	return func(c *config) {
+		c.preserveSpaces = true
+	}
+}
+
+// WithHeadingIDs renders each heading with an `id` attribute holding a slug of
+// its text, e.g. `<h1 id="the-quick-fox">The quick fox</h1>`, so headings can
+// be linked to. headings with the same text are numbered, e.g. `the-quick-fox-1`
+func WithHeadingIDs() Option {
+	return func(c *config) {
+		c.headingIDs = true
+	}
+}
+
+// WithHeadingIDPrefix sets a prefix for the ids rendered by `WithHeadingIDs`,
+// e.g. "intro-" for `intro-the-quick-fox`, so the ids of several documents
+// rendered into one page don't collide
+func WithHeadingIDPrefix(prefix string) Option {
+	return func(c *config) {
+		c.headingIDPrefix = prefix
+	}
+}
+
+// WithLinkTarget renders links to other sites, those with an http or https
+// url, with `target="_blank" rel="noopener noreferrer"` so they open in a new
+// tab. relative links are left to open in the same tab
+func WithLinkTarget() Option {
+	return func(c *config) {
+		c.linkTarget = true
+	}
+}
+
+// WithMaxHeadingText limits the heading text that the ids rendered by
+// `WithHeadingIDs` are made from to `n` characters, cut between words, so long
+// headings don't give very long ids. the heading itself is rendered in full.
+// the default is 0, which applies no limit
+func WithMaxHeadingText(n int) Option {
+	return func(c *config) {
+		c.maxHeadingText = n
+	}
+}
+
+// WithIndentUnit sets the indentation `ToRunic` writes for each level of a
+// nested list, which is either "\t" or at least `INDENT_WIDTH` spaces, the
+// default. a list indented by any less wouldn't be read back as nested, so any
+// other unit is ignored
+func WithIndentUnit(unit string) Option {
+	return func(c *config) {
+		if unit != "\t" && (len(unit) < INDENT_WIDTH || strings.Trim(unit, " ") != "") {
+			return
+		}
+		c.indentUnit = unit
+	}
+}
+
+// WithSemanticTags renders bold text as `<strong>` rather than `<b>`, so it
+// pairs with italic text, which is always rendered as `<em>`
+func WithSemanticTags() Option {
+	return func(c *config) {
+		c.semanticTags = true
+	}
+}
+
+// WithListDepth renders each list with a `data-depth` attribute holding its
+// nesting level, so nested lists can be styled separately
+func WithListDepth() Option {
+	return func(c *config) {
+		c.listDepth = true
+	}
+}
+
+// WithListLooseDetection renders the items of a list as paragraphs when a
+// blank line separates any of them, and as plain text otherwise
+func WithListLooseDetection() Option {
+	return func(c *config) {
+		c.looseLists = true
+	}
+}
+
+// WithListStartFromSource keeps the number written on each ordered list item,
+// rendering it as `<li value="...">` so `1.`, `3.`, `7.` aren't renumbered to
+// 1, 2, 3. `ToRunic` then writes the numbers back as they were
+func WithListStartFromSource() Option {
+	return func(c *config) {
+		c.listNumbers = true
+	}
+}
+
+// WithOrderedListContinuation resumes the numbering of an ordered list
+// interrupted by other blocks, so the list after `1. a`, `2. b` and a paragraph
+// starts at 3, rendered as `<ol start="3">`. a list beginning with `1.` starts
+// over instead
+func WithOrderedListContinuation() Option {
+	return func(c *config) {
+		c.listContinuation = true
+	}
+}
+
+// WithMicrodata renders schema.org microdata attributes on lists, describing
+// each list as an `ItemList` scope and each of its items as an
+// `itemListElement` of it. headings get none, as nothing around them is an
+// item for them to name
+func WithMicrodata() Option {
+	return func(c *config) {
+		c.microdata = true
+	}
+}
+
+// WithTabSize sets the width of a tab stop, used when measuring list
+// indentation and when rendering tabs in `HighlightText`. the default is 4,
+// which is kept when n is less than 1
+func WithTabSize(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			return
+		}
+		c.tabSize = n
+	}
+}
+
+// WithClassPrefix sets the prefix of the classes on the spans written by
+// `HighlightText` and `HighlightJSON`, e.g. "editor-" for `editor-text` and
+// `editor-tag`. the default is "runic__"
+func WithClassPrefix(prefix string) Option {
+	return func(c *config) {
+		c.classPrefix = prefix
+	}
+}
+
+// WithFlags sets the flags checked by `if[flag]{...}` blocks when rendering.
+// content is only included when its flag is true, unknown flags are false.
+// unlike tag names, flags are case-sensitive, so `if[Draft]` doesn't check
+// "draft". a block holds inline content, and one cut short by the end of its
+// paragraph or list item drops everything up to its `}`
+func WithFlags(flags map[string]bool) Option {
+	return func(c *config) {
+		c.flags = flags
+	}
+}
+
+// WithStrikethroughShorthand enables the `~~text~~` shorthand for striking
+// through text. a single `~` is always plain text
+func WithStrikethroughShorthand() Option {
+	return func(c *config) {
+		c.strikeShorthand = true
+	}
+}
+
+// WithCacheSize sets the number of rendered documents kept by `HtmlCached`.
+// the default is 128, and 0 disables caching
+func WithCacheSize(n int) Option {
+	return func(c *config) {
+		c.cacheSize = n
+	}
+}
+
+// WithStableErrorFormat formats the value of error nodes as
+// `code|message|detail`, e.g. `invalid_tag|Invalid tag name|foo`, so tools can
+// split them reliably. the same fields are available from `Diagnostics`
+func WithStableErrorFormat() Option {
+	return func(c *config) {
+		c.stableErrors = true
+	}
+}
+
+// WithVariables sets the values substituted for `var[name]` when rendering.
+// values are HTML escaped, and unknown variables render as nothing and are
+// reported by `Diagnostics`
+func WithVariables(variables map[string]string) Option {
+	return func(c *config) {
+		c.variables = variables
+	}
+}
+
+// WithMaxParagraphLength splits paragraphs longer than `n` characters into
+// several, at the end of a sentence where possible and otherwise between words.
+// tags are never split. the default is 0, which applies no limit
+func WithMaxParagraphLength(n int) Option {
+	return func(c *config) {
+		c.maxParagraphLength = n
+	}
+}
+
+// WithColorClasses maps the names used in `color(name)[...]` to the CSS classes
+// rendered on their spans, e.g. "red" to "runic__color-red". colors are only
+// ever rendered as classes, never inline styles, so the output suits a strict
+// Content-Security-Policy. text in an unknown color renders without a span
+func WithColorClasses(classes map[string]string) Option {
+	return func(c *config) {
+		c.colorClasses = classes
+	}
+}
+
+// WithMaxOutputBytes stops rendering HTML before the output exceeds `n`
+// bytes, which bounds the size of documents that expand a lot when rendered.
+// the output ends with the last piece which fit, so elements may be left
+// unclosed, and `WriteHtml` returns `ErrOutputTooLarge`. the default is 0,
+// which applies no limit
+func WithMaxOutputBytes(n int) Option {
+	return func(c *config) {
+		c.maxOutputBytes = n
+	}
+}
+
+// WithCustomTag defines an inline tag of the given name, rendered by writing
+// `htmlOpen` before its content and `htmlClose` after it, e.g. "spoiler" with
+// `<span class="spoiler">` and `</span>`. the HTML is written as given, so it
+// must come from the application rather than the author. like the built-in
+// tags, which can't be redefined, the name is case insensitive
+func WithCustomTag(name, htmlOpen, htmlClose string) Option {
+	return func(c *config) {
+		if c.customTags == nil {
+			c.customTags = map[string]customTag{}
+		}
+		c.customTags[canonicalTag(name)] = customTag{htmlOpen: htmlOpen, htmlClose: htmlClose}
+	}
+}
+
+// WithAutoLinks turns each bare URL in text into a link to it, so
+// `see https://example.com.` renders as
+// `see <a href="https://example.com">https://example.com</a>.`. only `http://`
+// and `https://` URLs are linked, punctuation ending a sentence is left out of
+// them, and the content of links and images is left alone
+func WithAutoLinks() Option {
+	return func(c *config) {
+		c.autoLinks = true
+	}
+}
+
+// EmptyTagMode decides what happens to a tag without content, such as `bold[]`
+type EmptyTagMode int
+
+const (
+	EmptyTagsDrop     EmptyTagMode = iota // leave the tag out of the tree
+	EmptyTagsError                        // replace the tag with an error node
+	EmptyTagsPreserve                     // keep the tag, rendering e.g. `<b></b>`
+)
+
+// WithEmptyTags sets how tags without content are handled. the default is
+// `EmptyTagsDrop`
+func WithEmptyTags(mode EmptyTagMode) Option {
+	return func(c *config) {
+		c.emptyTags = mode
+	}
+}
+
+// WithPreserveEmptyTags keeps tags without content, so `bold[]` renders as
+// `<b></b>`. it is the same as `WithEmptyTags(EmptyTagsPreserve)`
+func WithPreserveEmptyTags() Option {
+	return WithEmptyTags(EmptyTagsPreserve)
+}
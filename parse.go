@@ -2,40 +2,142 @@ package runic
 
 import (
 	"fmt"
+	"io"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 type parser struct {
-	input           string
 	tree            *Node
 	lexer           *lexer
 	error           string
 	currentNode     *Node
 	ctx             int
 	tagDepth        int
+	conditionDepth  int
+	strikeDepth     int
+	droppedSquares  int // closing squares left over from tags closed by the end of a line
 	collectedTokens []token
+	config          config
+	diagnostics     []Diagnostic
+	metadata        map[string]string // key/value pairs from the frontmatter, see `Metadata`
+	lastOrderedList *Node             // the last top level ordered list, see `WithOrderedListContinuation`
+	cache           *htmlCache
 }
 
-func New() *parser {
-	return &parser{}
+// Diagnostic describes a problem found while parsing, located at the line and
+// position in the input text where it begins. `Code` is stable across releases,
+// while `Message` is meant for people and may be reworded
+type Diagnostic struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail"`
+	Line    int    `json:"line"`
+	Pos     int    `json:"pos"`
+}
+
+func New(opts ...Option) *parser {
+	p := &parser{config: newConfig(opts...)}
+	if p.config.cacheSize > 0 {
+		p.cache = newHtmlCache(p.config.cacheSize)
+	}
+	return p
 }
 
 func (p *parser) Parse(input string) *Node {
-	p.lexer = lex(input)
+	p.lexer = lex(input, p.config)
 	p.tree = &Node{Typ: nodeRoot, Val: ""}
 	p.currentNode = p.tree
 	p.collectedTokens = []token{}
+	p.diagnostics = nil
+	p.metadata = map[string]string{}
+	p.lastOrderedList = nil
 	p.parseGlobal()
+	if p.config.autoLinks {
+		linkURLs(p.tree, p.config)
+	}
+	if p.config.maxParagraphLength > 0 {
+		splitLongParagraphs(p.tree, p.config.maxParagraphLength)
+	}
 	return p.tree
 }
 
-func (p *parser) addNewNode(typ, val string) {
-	newNode := &Node{
-		Typ:    typ,
-		Val:    val,
-		parent: p.currentNode,
+// ParseReader parses everything read from `r`, like `Parse`. the input is read
+// in full before parsing starts, and an error reading it is returned without
+// parsing anything
+func (p *parser) ParseReader(r io.Reader) (*Node, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(string(input)), nil
+}
+
+// ParseError is a diagnostic returned as an error by `ParseChecked`, located by
+// the column within its line as well as its position
+type ParseError struct {
+	Diagnostic
+	Column int // number of runes from the start of the line up to `Pos`, plus 1
+}
+
+func (e *ParseError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Message, e.Detail)
+}
+
+// ParseChecked parses the input like `Parse`, and also returns each problem
+// reported by `Diagnostics` as a `*ParseError`. there are no errors when the
+// input is valid
+func (p *parser) ParseChecked(input string) (*Node, []error) {
+	tree := p.Parse(input)
+	var errs []error
+	for _, diagnostic := range p.diagnostics {
+		lineStart := strings.LastIndexAny(input[:diagnostic.Pos], lineEndings) + 1
+		errs = append(errs, &ParseError{
+			Diagnostic: diagnostic,
+			Column:     RuneOffset(input[lineStart:], diagnostic.Pos-lineStart) + 1,
+		})
 	}
-	p.currentNode.Children = append(p.currentNode.Children, newNode)
+	return tree, errs
+}
+
+// Diagnostics returns the problems found during the most recent `Parse`
+func (p *parser) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+func (p *parser) addDiagnostic(message, detail string, t token) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Code:    errorCodes[message],
+		Message: message,
+		Detail:  detail,
+		Line:    t.Line,
+		Pos:     t.Pos,
+	})
+}
+
+// addErrorNode adds an error node and records it as a diagnostic at `t`
+func (p *parser) addErrorNode(message, detail string, t token) {
+	p.addNewNode(nodeError, p.errorValue(message, detail))
+	p.currentNode.setPosition(t)
+	p.addDiagnostic(message, detail, t)
+}
+
+// errorValue formats the value of an error node, see `WithStableErrorFormat`
+func (p *parser) errorValue(message, detail string) string {
+	if p.config.stableErrors {
+		return fmt.Sprintf("%s|%s|%s", errorCodes[message], message, detail)
+	}
+	return fmt.Sprintf("%s: %s", message, detail)
+}
+
+func (p *parser) addNewNode(typ, val string) {
+	newNode := newNode(typ, val)
+	newNode.setPosition(p.lexer.token)
+	p.currentNode.AppendChild(newNode)
 	p.currentNode = newNode
 }
 
@@ -52,7 +154,14 @@ func (p *parser) nextToken() {
 	p.collectedTokens = append(p.collectedTokens, p.lexer.token)
 }
 
+// returnNode returns to the parent of the current node. a block ends where the
+// token which ended it begins, while an inline node ends after its last token
 func (p *parser) returnNode() {
+	if isOneOf(p.currentNode.parent.Typ, nodeRoot, nodeList, nodeOrderedList, nodeBlockquote) || isOneOf(p.currentNode.Typ, nodeList, nodeOrderedList) {
+		p.currentNode.end = p.lexer.token.Pos
+	} else {
+		p.currentNode.end = p.lexer.tokenEnd()
+	}
 	p.currentNode = p.currentNode.parent
 }
 
@@ -62,13 +171,24 @@ func (p *parser) isOneOf(t ...tokenType) bool {
 
 func (p *parser) parseGlobal() {
 	p.tagDepth = 0
+	p.droppedSquares = 0
+	p.conditionDepth = 0
+	p.strikeDepth = 0
 	p.nextToken()
 	for !p.isOneOf(typeEOF) {
 		switch p.lexer.token.Typ {
 		case typeHeading:
 			p.parseHeading()
-		case typeBulletpoint:
+		case typeBulletpoint, typeNumberpoint:
 			p.parseList(0)
+		case typeQuote:
+			p.parseQuote()
+		case typeFrontmatter:
+			p.parseFrontmatter()
+		case typeComment:
+			// comments are only for authors, so they're left out of the tree
+		case typeRule:
+			p.parseRule()
 		default:
 			p.parseParagraph()
 		}
@@ -76,31 +196,136 @@ func (p *parser) parseGlobal() {
 	}
 }
 
+// parseRule adds a horizontal rule, which has no content and ends with its token
+func (p *parser) parseRule() {
+	p.addNewNode(nodeRule, "")
+	p.currentNode.end = p.lexer.tokenEnd()
+	p.currentNode = p.currentNode.parent
+}
+
+// parseParagraph parses rich text up to the end of the block into a paragraph.
+// a `paragraph[...]` tag ends the text before it, becomes a paragraph by
+// itself, and the text after it starts another one
 func (p *parser) parseParagraph() {
+	// a terminator only begins a paragraph kept empty by
+	// `WithPreserveEmptyParagraphs`
+	preserved := p.isOneOf(typeTerminator)
 	p.addNewNode(nodeParagraph, "")
 	p.parseRichText()
+	p.dropTrailingLineBreak()
 	p.returnNode()
+	// any other is left empty by dropping its content, such as tags dropped by
+	// `WithEmptyTags`, and has nothing to show
+	if !preserved {
+		p.dropEmptyParagraph()
+	}
+
+	for p.isParagraphTag() {
+		p.dropEmptyParagraph()
+		p.parseParagraphTag()
+		if !p.isOneOf(typeClosingSquare) {
+			return
+		}
+
+		p.nextToken()
+		p.addNewNode(nodeParagraph, "")
+		p.parseRichText()
+		p.dropTrailingLineBreak()
+		p.returnNode()
+		p.dropEmptyParagraph()
+	}
+}
+
+// isParagraphTag reports whether the current token opens a `paragraph[...]`
+// tag outside of any inline element
+func (p *parser) isParagraphTag() bool {
+	return p.isOneOf(typeTag) && canonicalTag(p.lexer.token.Val) == tagParagraph && !p.isUnclosed()
+}
+
+// parseParagraphTag parses `paragraph[...]` into a paragraph holding the
+// content of the tag
+func (p *parser) parseParagraphTag() {
+	paragraphTag := p.lexer.token
+	p.addNewNode(nodeParagraph, "")
+
+	// skip over openSquare token
+	p.nextToken()
+	openingSquare := p.lexer.token
+	p.tagDepth++
+
+	p.parseRichText()
+	p.dropTrailingLineBreak()
+	// a paragraph left open is closed by the end of its block
+	if !p.isOneOf(typeClosingSquare) {
+		p.addDiagnostic(errUnclosedTag, tagParagraph, openingSquare)
+		p.tagDepth = 0
+	}
+	p.returnTag(tagParagraph, paragraphTag)
+}
+
+// dropTrailingLineBreak removes the line breaks ending the current paragraph,
+// which leave no line after them to break, as when a `paragraph[...]` tag
+// follows on the next line
+func (p *parser) dropTrailingLineBreak() {
+	for last := p.previousSibling(); last.Typ == nodeLineBreak; last = p.previousSibling() {
+		p.currentNode.Children = p.currentNode.Children[:len(p.currentNode.Children)-1]
+	}
+}
+
+// dropEmptyParagraph removes the last paragraph if it has no content, as when
+// nothing but whitespace surrounds a `paragraph[...]` tag
+func (p *parser) dropEmptyParagraph() {
+	if last := p.previousSibling(); last.Typ == nodeParagraph && len(last.Children) == 0 {
+		p.currentNode.Children = p.currentNode.Children[:len(p.currentNode.Children)-1]
+	}
 }
 
 func (p *parser) parseText() {
 	previousSibling := p.previousSibling()
-	// merge consecutive text nodes into one
+	// merge consecutive text nodes into one, such as the text either side of a
+	// stray square
 	if previousSibling.Typ == nodeText {
-		previousSibling.Val += " " + p.lexer.token.Val
+		if !p.lexer.isJoined(p.lexer.token) {
+			previousSibling.Val += " "
+		}
+		previousSibling.Val += p.lexer.token.Val
+		previousSibling.end = p.lexer.tokenEnd()
 		return
 	}
 	p.addNewNode(nodeText, p.lexer.token.Val)
+	// text directly after a tag, such as the `!` of `bold[word]!`, is joined to
+	// it. the first node of a block or tag has nothing to be joined to
+	p.currentNode.Joined = previousSibling.Typ != "" && p.lexer.isJoined(p.lexer.token)
 	p.returnNode()
 }
 
+// isUnclosed reports whether the parser is inside an inline element which has
+// not been closed yet
+func (p *parser) isUnclosed() bool {
+	return p.tagDepth > 0 || p.conditionDepth > 0 || p.strikeDepth > 0
+}
+
 func (p *parser) parseRichText() {
-	for !p.isOneOf(typeBulletpoint, typeTerminator, typeEOF) {
+	for !p.isOneOf(typeBulletpoint, typeNumberpoint, typeQuote, typeTerminator, typeEOF) {
 		switch p.lexer.token.Typ {
 		case typeText:
 			p.parseText()
 		case typeTag:
+			// a paragraph tag ends the paragraph it is in, unless it's nested
+			if p.isParagraphTag() && p.currentNode.Typ == nodeParagraph && p.currentNode.parent.Typ == nodeRoot {
+				return
+			}
 			p.parseTag()
-			if p.tagDepth > 0 && p.isOneOf(typeBulletpoint, typeTerminator) {
+			if p.isUnclosed() && p.isOneOf(typeBulletpoint, typeNumberpoint, typeQuote, typeTerminator) {
+				return
+			}
+		case typeStrike:
+			if p.strikeDepth > 0 {
+				p.strikeDepth--
+				return
+			}
+			p.parseStrike()
+			if p.isUnclosed() && p.isOneOf(typeBulletpoint, typeNumberpoint, typeQuote, typeTerminator) {
 				return
 			}
 		case typeClosingSquare:
@@ -108,13 +333,43 @@ func (p *parser) parseRichText() {
 				p.tagDepth--
 				return
 			}
+			if p.droppedSquares > 0 {
+				p.droppedSquares--
+				break
+			}
+			p.parseText()
+		case typeOpeningSquare:
+			if p.isStraySquare() {
+				p.parseText()
+			}
+		case typeClosingCurly:
+			if p.conditionDepth > 0 {
+				p.conditionDepth--
+				return
+			}
+		case typeLineBreak:
+			p.addNewNode(nodeLineBreak, "")
+			p.returnNode()
 		}
 		p.nextToken()
 	}
 }
 
+// isStraySquare reports whether the current opening square doesn't belong to
+// a tag, which leaves its content to be parsed starting from the square
+func (p *parser) isStraySquare() bool {
+	if len(p.collectedTokens) < 2 {
+		return true
+	}
+	previous := p.collectedTokens[len(p.collectedTokens)-2]
+	return previous.Typ != typeTag && previous.Typ != typeTagArg
+}
+
+// parseHeading parses a heading marker and the rich text following it on the
+// same line. a marker without any text produces an empty heading rather than
+// an error, since authors often leave one while typing
 func (p *parser) parseHeading() {
-	switch p.lexer.token.Val {
+	switch headingValue(p.lexer.token.Val) {
 	case nodeHeadingOneValue:
 		p.addNewNode(nodeHeadingOne, nodeHeadingOneValue)
 	case nodeHeadingTwoValue:
@@ -128,7 +383,7 @@ func (p *parser) parseHeading() {
 	case nodeHeadingSixValue:
 		p.addNewNode(nodeHeadingSix, nodeHeadingSixValue)
 	default:
-		p.addNewNode(nodeError, fmt.Sprintf("%s: %s", errInvalidHeading, p.lexer.token.Val))
+		p.addErrorNode(errInvalidHeading, p.lexer.token.Val, p.lexer.token)
 	}
 
 	p.nextToken()
@@ -136,46 +391,196 @@ func (p *parser) parseHeading() {
 	p.returnNode()
 }
 
+// headingValue returns the heading value the given marker is read as, which is
+// `nodeHeadingSixValue` for any longer run of colons, with or without a dot
+func headingValue(marker string) string {
+	colons := strings.TrimSuffix(marker, string(charDot))
+	if strings.HasPrefix(colons, nodeHeadingSixValue) && strings.Trim(colons, string(charColon)) == "" {
+		return nodeHeadingSixValue
+	}
+	return marker
+}
+
 func (p *parser) parseTag() {
-	switch p.lexer.token.Val {
+	name := canonicalTag(p.lexer.token.Val)
+	if name == tagCondition {
+		p.parseCondition()
+		return
+	}
+	if name == tagVariable {
+		p.parseVariable()
+		return
+	}
+	if name == tagCode {
+		p.parseCode()
+		return
+	}
+
+	tagToken := p.lexer.token
+	switch name {
 	case "bold":
 		p.addNewNode(nodeBoldTag, "")
 	case "italic":
 		p.addNewNode(nodeItalicTag, "")
+	case "underline":
+		p.addNewNode(nodeUnderlineTag, "")
+	case "kbd":
+		p.addNewNode(nodeKbdTag, "")
+	case "mark":
+		p.addNewNode(nodeMarkTag, "")
+	case "strike":
+		p.addNewNode(nodeStrikeTag, "")
+	case tagLink:
+		p.addArgNode(nodeLinkTag, errInvalidLink, isAllowedURL)
+	case tagImage:
+		p.addArgNode(nodeImageTag, errInvalidImage, isAllowedURL)
+	case tagColor:
+		p.addColorNode()
 	default:
-		p.addNewNode(nodeError, fmt.Sprintf("%s: %s", errInvalidTag, p.lexer.token.Val))
+		if _, ok := p.config.customTags[name]; ok {
+			p.addNewNode(nodeCustomTag, name)
+			break
+		}
+		p.addErrorNode(errInvalidTag, p.lexer.token.Val, p.lexer.token)
 	}
 
+	tagName := tagToken.Val
+	p.currentNode.setPosition(tagToken)
+	p.currentNode.Joined = p.lexer.isJoined(tagToken)
+
 	// skip over openSquare token
 	p.nextToken()
+	openingSquare := p.lexer.token
 	p.tagDepth++
 
 	p.parseRichText()
+	// the tag was closed by the end of its block rather than a closing square
+	if !p.isOneOf(typeClosingSquare) {
+		p.addDiagnostic(errUnclosedTag, tagName, openingSquare)
+	}
+	p.returnTag(tagName, tagToken)
+}
+
+// allowedURLSchemes are the only schemes the url of a link or image may have,
+// so that one such as `javascript:` can't run script. a url without a scheme is
+// relative, and always allowed
+var allowedURLSchemes = []string{"http", "https", "mailto"}
+
+// isAllowedURL reports whether the url is relative or has one of
+// `allowedURLSchemes`
+func isAllowedURL(url string) bool {
+	scheme := urlScheme(url)
+	return scheme == "" || slices.Contains(allowedURLSchemes, scheme)
+}
+
+// urlScheme returns the lowercased scheme of the url, or "" for a relative url.
+// a colon before any slash, query or fragment ends the scheme
+func urlScheme(url string) string {
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return ""
+	}
+	return strings.ToLower(url[:i])
+}
+
+// addArgNode adds a node of the given type holding the tag argument, such as
+// the url of a link, and reports whether it did. when the argument is missing,
+// empty, or rejected by `valid`, an error node with the given message is added
+// instead. the current token is left on the last token before the opening
+// square
+func (p *parser) addArgNode(typ, message string, valid func(string) bool) bool {
+	tag := p.lexer.token
+	arg := ""
+	if p.lexer.peek() == charOpeningParen {
+		p.nextToken()
+		arg = p.lexer.token.Val
+	}
+
+	if arg == "" || valid != nil && !valid(arg) {
+		p.addErrorNode(message, arg, tag)
+		return false
+	}
+	p.addNewNode(typ, arg)
+	return true
+}
+
+// addColorNode adds a color tag holding the color name from the tag argument,
+// like `addArgNode`, and notes a name without a class from `WithColorClasses`
+func (p *parser) addColorNode() {
+	colorTag := p.lexer.token
+	if !p.addArgNode(nodeColorTag, errInvalidColor, nil) {
+		return
+	}
+	if _, ok := p.config.colorClasses[p.currentNode.Val]; !ok {
+		p.addDiagnostic(errUnknownColor, p.currentNode.Val, colorTag)
+	}
+}
+
+// returnTag returns from a tag node like `returnNode`. an empty tag is then
+// dropped, kept, or turned into an error node depending on `WithEmptyTags`. an
+// image without alt text is still an image, so it is always kept
+func (p *parser) returnTag(name string, t token) {
+	tag := p.currentNode
 	p.returnNode()
+	if len(tag.Children) > 0 || tag.Typ == nodeCodeTag && tag.Val != "" || tag.Typ == nodeError || tag.Typ == nodeImageTag {
+		return
+	}
+
+	switch p.config.emptyTags {
+	case EmptyTagsDrop:
+		p.currentNode.Children = p.currentNode.Children[:len(p.currentNode.Children)-1]
+	case EmptyTagsError:
+		tag.Typ = nodeError
+		tag.Val = p.errorValue(errEmptyTag, name)
+		p.addDiagnostic(errEmptyTag, name, t)
+	}
 }
 
 func (p *parser) parseList(currentListDepth int) {
-	if p.isOneOf(typeBulletpoint) && getListItemDepth(p.lexer.token) < currentListDepth {
+	if p.isListMarker() && getListItemDepth(p.lexer.token) < currentListDepth {
 		p.returnNode()
 		return
 	}
 
-	p.addNewNode(nodeList, "")
+	p.addListNode(listTypeOf(p.lexer.token))
 
-	for p.isOneOf(typeBulletpoint) {
+	for p.isListMarker() {
 		currentListDepth = getListItemDepth(p.lexer.token)
 
+		// the marker changed at the same depth, end the list and start another
+		if listType := listTypeOf(p.lexer.token); listType != p.currentNode.Typ {
+			p.returnNode()
+			p.addListNode(listType)
+		}
+
+		if p.lexer.token.blankLine && len(p.currentNode.Children) > 0 {
+			p.currentNode.Loose = true
+		}
+		// a blank line before the first item of a nested list separates it
+		// from the content of the item it is in, loosening the outer list
+		if item := p.currentNode.parent; p.lexer.token.blankLine && len(p.currentNode.Children) == 0 && item.Typ == nodeListItem {
+			item.parent.Loose = true
+		}
+
+		marker := p.lexer.token
 		p.nextToken()
-		p.parseListItem()
+		p.parseListItem(marker)
+		// a tag left open is closed by the end of its item, and the closing
+		// square meant for it is dropped from whichever later item it is in
+		p.droppedSquares += p.tagDepth
 		p.tagDepth = 0
+		p.conditionDepth = 0
+		p.strikeDepth = 0
 
-		// bulletpoint is at a lower depth, create nested list
-		if p.isOneOf(typeBulletpoint) && getListItemDepth(p.lexer.token) > currentListDepth {
+		// bulletpoint is at a lower depth, create a list nested in the item
+		if p.isListMarker() && getListItemDepth(p.lexer.token) > currentListDepth {
+			p.currentNode = p.previousSibling()
 			p.parseList(getListItemDepth(p.lexer.token))
+			p.returnNode()
 		}
 
 		// bulletpoint is a higher depth, return until no longer shallower
-		if p.isOneOf(typeBulletpoint) && getListItemDepth(p.lexer.token) < currentListDepth {
+		if p.isListMarker() && getListItemDepth(p.lexer.token) < currentListDepth {
 			p.returnNode()
 			return
 		}
@@ -184,8 +589,268 @@ func (p *parser) parseList(currentListDepth int) {
 	p.returnNode()
 }
 
-func (p *parser) parseListItem() {
+// isListMarker reports whether the current token begins a list item, in either
+// an unordered or an ordered list
+func (p *parser) isListMarker() bool {
+	return p.isOneOf(typeBulletpoint, typeNumberpoint)
+}
+
+// addListNode adds a list of the given type, nested one level deeper than the
+// list of the item it is in. a top level ordered list resumes the numbering of
+// the one before it with `WithOrderedListContinuation`
+func (p *parser) addListNode(typ string) {
+	marker := p.lexer.token
+	p.addNewNode(typ, "")
+	if item := p.currentNode.parent; item.Typ == nodeListItem {
+		p.currentNode.Depth = item.parent.Depth + 1
+	}
+	if typ != nodeOrderedList || p.currentNode.parent != p.tree || !p.config.listContinuation {
+		return
+	}
+	if previous := p.lastOrderedList; previous != nil && numberOf(marker) != 1 {
+		p.currentNode.Start = max(previous.Start, 1) + len(previous.Children)
+	}
+	p.lastOrderedList = p.currentNode
+}
+
+// numberOf returns the number of an ordered list item's marker
+func numberOf(marker token) int {
+	number, _ := strconv.Atoi(strings.TrimSuffix(marker.Val, string(charDot)))
+	return number
+}
+
+// listTypeOf returns the type of list the given marker begins
+func listTypeOf(marker token) string {
+	if marker.Typ == typeNumberpoint {
+		return nodeOrderedList
+	}
+	return nodeList
+}
+
+// parseListItem parses the item begun by the given marker, keeping its number
+// for `WithListStartFromSource`
+func (p *parser) parseListItem(marker token) {
 	p.addNewNode(nodeListItem, "")
+	if marker.Typ == typeNumberpoint && p.config.listNumbers {
+		p.currentNode.Number = numberOf(marker)
+	}
+	if p.isOneOf(typeCheckbox) {
+		p.currentNode.Task = true
+		p.currentNode.Checked = p.lexer.token.Val == checkboxChecked
+		p.nextToken()
+	}
 	p.parseRichText()
 	p.returnNode()
 }
+
+// parseQuote parses consecutive quote lines into a blockquote. the lines are
+// joined into paragraphs, which are separated by quote lines without any text
+func (p *parser) parseQuote() {
+	p.addNewNode(nodeBlockquote, "")
+
+	var paragraph *Node
+	for p.isOneOf(typeQuote) {
+		p.nextToken()
+		if p.isOneOf(typeQuote, typeTerminator, typeEOF) {
+			paragraph = nil
+			continue
+		}
+
+		// continue the paragraph from the previous line
+		if paragraph == nil {
+			p.addNewNode(nodeParagraph, "")
+			paragraph = p.currentNode
+		} else {
+			p.currentNode = paragraph
+		}
+		p.parseRichText()
+		p.droppedSquares += p.tagDepth
+		p.tagDepth = 0
+		p.conditionDepth = 0
+		p.strikeDepth = 0
+		p.returnNode()
+		if len(paragraph.Children) == 0 {
+			p.dropEmptyParagraph()
+			paragraph = nil
+		}
+	}
+
+	p.returnNode()
+}
+
+// parseCondition parses `if[flag]{...}`. the flag is stored as the node's value
+// and whether the content is included is decided when rendering
+func (p *parser) parseCondition() {
+	conditionTag := p.lexer.token
+
+	// skip over tag and openSquare tokens
+	p.nextToken()
+	p.nextToken()
+
+	flag := ""
+	if p.isOneOf(typeText) {
+		flag = p.lexer.token.Val
+		p.nextToken()
+	}
+
+	// the flag isn't plain text, recover by treating the rest as an invalid tag
+	if !p.isOneOf(typeClosingSquare) {
+		p.addErrorNode(errInvalidCondition, flag, conditionTag)
+		if flag != "" {
+			p.addNewNode(nodeText, flag)
+			p.returnNode()
+		}
+		p.tagDepth++
+		p.parseRichText()
+		p.returnNode()
+		return
+	}
+
+	hasBlock := p.lexer.peek() == charOpeningCurly
+	if flag == "" || !hasBlock {
+		p.addErrorNode(errInvalidCondition, flag, conditionTag)
+	} else {
+		p.addNewNode(nodeConditionTag, flag)
+		p.currentNode.setPosition(conditionTag)
+	}
+
+	if hasBlock {
+		// skip over closingSquare token
+		p.nextToken()
+		openingCurly := p.lexer.token
+		tagDepth, strikeDepth := p.tagDepth, p.strikeDepth
+		p.conditionDepth++
+
+		p.parseRichText()
+		if !p.isOneOf(typeClosingCurly) {
+			// the block ended inside the condition, so what follows up to its
+			// closing curly is dropped rather than shown whatever the flag.
+			// tags left open inside it are closed by the curly
+			cut := p.lexer.token
+			if p.skipToClosingCurly() {
+				p.addDiagnostic(errCutCondition, flag, cut)
+				p.tagDepth, p.strikeDepth = tagDepth, strikeDepth
+				p.conditionDepth--
+			} else {
+				p.addDiagnostic(errUnclosedCondition, flag, openingCurly)
+			}
+		}
+	}
+	p.returnNode()
+}
+
+// skipToClosingCurly skips over the tokens up to the curly closing the current
+// condition and reports whether there is one. nothing is skipped if not
+func (p *parser) skipToClosingCurly() bool {
+	lexer := *p.lexer
+	depth := 1
+	for p.lexer.nextToken() {
+		switch p.lexer.token.Typ {
+		case typeOpeningCurly:
+			depth++
+		case typeClosingCurly:
+			depth--
+		}
+		if depth == 0 {
+			p.collectedTokens = append(p.collectedTokens, p.lexer.token)
+			return true
+		}
+	}
+	*p.lexer = lexer
+	return false
+}
+
+// parseVariable parses `var[name]`. the name is stored as the node's value and
+// substituted when rendering
+func (p *parser) parseVariable() {
+	variableTag := p.lexer.token
+
+	// skip over tag and openSquare tokens
+	p.nextToken()
+	p.nextToken()
+
+	name := ""
+	if p.isOneOf(typeText) {
+		name = p.lexer.token.Val
+		p.nextToken()
+	}
+
+	// the name isn't plain text, recover by treating the rest as an invalid tag
+	if !p.isOneOf(typeClosingSquare) {
+		p.addErrorNode(errInvalidVariable, name, variableTag)
+		if name != "" {
+			p.addNewNode(nodeText, name)
+			p.returnNode()
+		}
+		p.tagDepth++
+		p.parseRichText()
+		p.returnNode()
+		return
+	}
+
+	if name == "" {
+		p.addErrorNode(errInvalidVariable, name, variableTag)
+	} else {
+		p.addNewNode(nodeVariableTag, name)
+		p.currentNode.setPosition(variableTag)
+		p.currentNode.Joined = p.lexer.isJoined(variableTag)
+		if _, ok := p.config.variables[name]; !ok {
+			p.addDiagnostic(errUnknownVariable, name, variableTag)
+		}
+	}
+	p.returnNode()
+}
+
+// parseCode parses `code[...]`. everything up to the matching closing square is
+// kept as the node's value, so tags inside it are shown rather than parsed
+func (p *parser) parseCode() {
+	codeTag := p.lexer.token
+	p.addNewNode(nodeCodeTag, "")
+	p.currentNode.Joined = p.lexer.isJoined(codeTag)
+
+	// skip over tag and openSquare tokens
+	p.nextToken()
+	openingSquare := p.lexer.token
+	p.nextToken()
+
+	// squares inside the code must be balanced to be part of it
+	depth := 0
+	for !p.isOneOf(typeBulletpoint, typeNumberpoint, typeQuote, typeTerminator, typeEOF) {
+		if p.isOneOf(typeOpeningSquare) {
+			depth++
+		} else if p.isOneOf(typeClosingSquare) {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		p.nextToken()
+	}
+
+	code := p.lexer.input[openingSquare.Pos+len(openingSquare.Val) : p.lexer.token.Pos]
+	p.currentNode.Val = strings.Join(strings.Fields(code), " ")
+
+	// the code was closed by the end of its block, which has to end any tags
+	// around it too
+	if !p.isOneOf(typeClosingSquare) {
+		p.addDiagnostic(errUnclosedTag, tagCode, openingSquare)
+		p.tagDepth++
+	}
+	p.returnTag(tagCode, codeTag)
+}
+
+// parseStrike parses the `~~...~~` shorthand into a strike tag
+func (p *parser) parseStrike() {
+	p.addNewNode(nodeStrikeTag, "")
+
+	// skip over opening strike token
+	openingStrike := p.lexer.token
+	p.nextToken()
+	p.strikeDepth++
+
+	p.parseRichText()
+	if !p.isOneOf(typeStrike) {
+		p.addDiagnostic(errUnclosedTag, strikeShorthand, openingStrike)
+	}
+	p.returnTag(strikeShorthand, openingStrike)
+}
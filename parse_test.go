@@ -2,8 +2,12 @@ package runic
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type parseTest struct {
@@ -83,8 +87,8 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"only rich text",
-		"bold[fox]",
+		"nested rich text w/ underline",
+		"The quick underline[brown fox bold[jumps] over the] lazy italic[underline[dog]]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
@@ -92,11 +96,46 @@ var parseTests = []parseTest{
 					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeBoldTag,
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeUnderlineTag,
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "fox",
+									Val: "brown fox",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "jumps",
+										},
+									},
+								},
+								{
+									Typ: nodeText,
+									Val: "over the",
+								},
+							},
+						},
+						{
+							Typ: nodeText,
+							Val: "lazy",
+						},
+						{
+							Typ: nodeItalicTag,
+							Children: []*Node{
+								{
+									Typ: nodeUnderlineTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "dog",
+										},
+									},
 								},
 							},
 						},
@@ -106,8 +145,8 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"rich text with invalid tag",
-		"The quick foo[brown fox jumps over the] lazy dog",
+		"kbd tags",
+		"Press kbd[Ctrl]+kbd[C]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
@@ -116,21 +155,29 @@ var parseTests = []parseTest{
 					Children: []*Node{
 						{
 							Typ: nodeText,
-							Val: "The quick",
+							Val: "Press",
 						},
 						{
-							Typ: nodeError,
-							Val: "Invalid tag name: foo",
+							Typ: nodeKbdTag,
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "brown fox jumps over the",
+									Val: "Ctrl",
 								},
 							},
 						},
 						{
 							Typ: nodeText,
-							Val: "lazy dog",
+							Val: "+",
+						},
+						{
+							Typ: nodeKbdTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "C",
+								},
+							},
 						},
 					},
 				},
@@ -138,8 +185,8 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"multiple paragraphs",
-		"The quick brown fox\n\njumps over the lazy dog",
+		"tags w/ mixed case names",
+		"Bold[x] ITALIC[y] b[z]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
@@ -147,17 +194,32 @@ var parseTests = []parseTest{
 					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "The quick brown fox",
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "x",
+								},
+							},
 						},
-					},
-				},
-				{
-					Typ: nodeParagraph,
-					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "jumps over the lazy dog",
+							Typ: nodeItalicTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "y",
+								},
+							},
+						},
+						{
+							Typ: nodeError,
+							Val: fmt.Sprintf("%s: b", errInvalidTag),
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "z",
+								},
+							},
 						},
 					},
 				},
@@ -165,18 +227,27 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"level one heading",
-		". This is a level one heading",
+		"mark nested in bold",
+		"bold[mark[both]]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeHeadingOne,
-					Val: ".",
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "This is a level one heading",
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeMarkTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "both",
+										},
+									},
+								},
+							},
 						},
 					},
 				},
@@ -184,36 +255,72 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"level one heading with paragraphs underneath",
-		". This is a level one heading\nThe quick brown fox jumps over the lazy dog\n\nLorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		"nested same-type tags",
+		"bold[a bold[b] c]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeHeadingOne,
-					Val: ".",
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "This is a level one heading",
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "b",
+										},
+									},
+								},
+								{
+									Typ: nodeText,
+									Val: "c",
+								},
+							},
 						},
 					},
 				},
+			},
+		},
+	},
+	{
+		"strike",
+		"bold[strike[gone]] strike[removed]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
 				{
 					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "The quick brown fox jumps over the lazy dog",
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeStrikeTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "gone",
+										},
+									},
+								},
+							},
 						},
-					},
-				},
-				{
-					Typ: nodeParagraph,
-					Children: []*Node{
 						{
-							Typ: nodeText,
-							Val: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+							Typ: nodeStrikeTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "removed",
+								},
+							},
 						},
 					},
 				},
@@ -221,21 +328,25 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"invalid heading with paragraph underneath",
-		"..\na",
+		"code",
+		"The code[bold[x] [y]] fox",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
-				{
-					Typ: nodeError,
-					Val: fmt.Sprintf("%s: ..", errInvalidHeading),
-				},
 				{
 					Typ: nodeParagraph,
 					Children: []*Node{
 						{
 							Typ: nodeText,
-							Val: "a",
+							Val: "The",
+						},
+						{
+							Typ: nodeCodeTag,
+							Val: "bold[x] [y]",
+						},
+						{
+							Typ: nodeText,
+							Val: "fox",
 						},
 					},
 				},
@@ -243,84 +354,128 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"list",
-		"- Item one\n-Item two\n-Item three",
+		"link",
+		"See link(https://example.com)[the bold[docs]] now",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "Item one",
-								},
-							},
+							Typ: nodeText,
+							Val: "See",
 						},
 						{
-							Typ: nodeListItem,
+							Typ: nodeLinkTag,
+							Val: "https://example.com",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item two",
+									Val: "the",
 								},
-							},
-						},
-						{
-							Typ: nodeListItem,
-							Children: []*Node{
 								{
-									Typ: nodeText,
-									Val: "Item three",
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "docs",
+										},
+									},
 								},
 							},
 						},
+						{
+							Typ: nodeText,
+							Val: "now",
+						},
 					},
 				},
 			},
 		},
 	},
 	{
-		"list with indents",
-		"         \n        \n  - Item one\n                  - Item two    \n        - Item three",
+		"link without url",
+		"See link[docs] now",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
+							Typ: nodeText,
+							Val: "See",
+						},
+						{
+							Typ: nodeError,
+							Val: "Invalid link: ",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item one",
+									Val: "docs",
 								},
 							},
 						},
 						{
-							Typ: nodeList,
+							Typ: nodeText,
+							Val: "now",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"image inside link",
+		"link(https://x)[image(https://y.png)[alt]]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeLinkTag,
+							Val: "https://x",
 							Children: []*Node{
 								{
-									Typ: nodeListItem,
+									Typ: nodeImageTag,
+									Val: "https://y.png",
 									Children: []*Node{
 										{
 											Typ: nodeText,
-											Val: "Item two",
+											Val: "alt",
 										},
 									},
 								},
 							},
 						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"link w/ javascript url",
+		"See link(javascript:alert`1`)[click]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "See",
+						},
 						{
-							Typ: nodeListItem,
+							Typ: nodeError,
+							Val: "Invalid link: javascript:alert`1`",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item three",
+									Val: "click",
 								},
 							},
 						},
@@ -330,71 +485,1876 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"list with indents v2",
-		"- Item one\n  - Item two\n    - Item three\n      - Item four\n  - Item five",
+		"image",
+		"A image(https://x/y.png)[a cat]",
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
+							Typ: nodeText,
+							Val: "A",
+						},
+						{
+							Typ: nodeImageTag,
+							Val: "https://x/y.png",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item one",
+									Val: "a cat",
 								},
 							},
 						},
-						{
-							Typ: nodeList,
-							Children: []*Node{
-								{
-									Typ: nodeListItem,
-									Children: []*Node{
-										{
-											Typ: nodeText,
-											Val: "Item two",
-										},
-									},
-								},
+					},
+				},
+			},
+		},
+	},
+	{
+		"image w/o alt text",
+		"image(y.png)[]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeImageTag,
+							Val: "y.png",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"image without url",
+		"image[a cat]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeError,
+							Val: "Invalid image: ",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a cat",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"image w/ data url",
+		"image(data:image/png;base64,AAAA)[a cat]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeError,
+							Val: "Invalid image: data:image/png;base64,AAAA",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a cat",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rich text w/ escaped squares",
+		"bold[a \\[ b \\] c] d",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a [ b ] c",
+								},
+							},
+						},
+						{
+							Typ: nodeText,
+							Val: "d",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"only rich text",
+		"bold[fox]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "fox",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rich text with invalid tag",
+		"The quick foo[brown fox jumps over the] lazy dog",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeError,
+							Val: "Invalid tag name: foo",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "brown fox jumps over the",
+								},
+							},
+						},
+						{
+							Typ: nodeText,
+							Val: "lazy dog",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"multiple paragraphs",
+		"The quick brown fox\n\njumps over the lazy dog",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown fox",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "jumps over the lazy dog",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"level one heading",
+		". This is a level one heading",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingOne,
+					Val: ".",
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "This is a level one heading",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"level one heading with paragraphs underneath",
+		". This is a level one heading\nThe quick brown fox jumps over the lazy dog\n\nLorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingOne,
+					Val: ".",
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "This is a level one heading",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown fox jumps over the lazy dog",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"invalid heading with paragraph underneath",
+		"..\na",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeError,
+					Val: fmt.Sprintf("%s: ..", errInvalidHeading),
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "a",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"mixed invalid heading with text and list underneath",
+		".:. The quick fox\n- Item one",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeError,
+					Val: fmt.Sprintf("%s: .:.", errInvalidHeading),
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick fox",
+						},
+					},
+				},
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"heading six with excessive characters and paragraph underneath",
+		"::::::\nThe quick fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingSix,
+					Val: nodeHeadingSixValue,
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"heading deeper than six",
+		":::: The quick fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingSix,
+					Val: nodeHeadingSixValue,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"headings deeper than six",
+		"::::: The quick fox\n::::. jumps over\n:::.. the lazy dog",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingSix,
+					Val: nodeHeadingSixValue,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick fox",
+						},
+					},
+				},
+				{
+					Typ: nodeHeadingSix,
+					Val: nodeHeadingSixValue,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "jumps over",
+						},
+					},
+				},
+				{
+					Typ: nodeError,
+					Val: fmt.Sprintf("%s: :::..", errInvalidHeading),
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "the lazy dog",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"invalid headings separated by blank lines",
+		"..\n\n:..\n\n. Title",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeError,
+					Val: fmt.Sprintf("%s: ..", errInvalidHeading),
+				},
+				{
+					Typ: nodeError,
+					Val: fmt.Sprintf("%s: :..", errInvalidHeading),
+				},
+				{
+					Typ: nodeHeadingOne,
+					Val: nodeHeadingOneValue,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Title",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list",
+		"- Item one\n-Item two\n-Item three",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item two",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item three",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list with indents",
+		"         \n        \n  - Item one\n                  - Item two    \n        - Item three",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item three",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list with indents v2",
+		"- Item one\n  - Item two\n    - Item three\n      - Item four\n  - Item five",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+												{
+													Typ: nodeList,
+													Children: []*Node{
+														{
+															Typ: nodeListItem,
+															Children: []*Node{
+																{
+																	Typ: nodeText,
+																	Val: "Item three",
+																},
+																{
+																	Typ: nodeList,
+																	Children: []*Node{
+																		{
+																			Typ: nodeListItem,
+																			Children: []*Node{
+																				{
+																					Typ: nodeText,
+																					Val: "Item four",
+																				},
+																			},
+																		},
+																	},
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item five",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list with indents v3",
+		`
+        - Item one
+          - Item two
+            - Item three
+          - Item four
+        - Item five
+    `,
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+												{
+													Typ: nodeList,
+													Children: []*Node{
+														{
+															Typ: nodeListItem,
+															Children: []*Node{
+																{
+																	Typ: nodeText,
+																	Val: "Item three",
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item four",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item five",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list with paragraph underneath",
+		" - a\nb",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a",
+								},
+							},
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "b",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list items string literal",
+		`
+	       - Item one
+	       - Item two
+	  `,
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item two",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list items string literal v2",
+		`
+	       - Item one
+           - Item two
+	  `,
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item two",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rich text over 2 list items",
+		"- The bold[quick\n- brown fox] jumps",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "The",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "quick",
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "brown fox jumps",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rich text over 3 list items",
+		"- The bold[quick\n- brown\n- fox] jumps\n- over",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "The",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "quick",
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "brown",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "fox jumps",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "over",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rich text over 2 list items v2",
+		"- The italic[bold[quick\n- brown fox] jumps",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "The",
+								},
+								{
+									Typ: nodeItalicTag,
+									Children: []*Node{
+										{
+											Typ: nodeBoldTag,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "quick",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "brown fox jumps",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"escaped newline",
+		"你好\\\n世界\n\nThe quick brown fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "你好世界",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"line break",
+		"The quick brown\\\\\nbold[fox] jumps\nover the lazy dog",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown",
+						},
+						{
+							Typ: nodeLineBreak,
+						},
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "fox",
+								},
+							},
+						},
+						{
+							Typ: nodeText,
+							Val: "jumps over the lazy dog",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"line break before paragraph tag",
+		"The quick brown fox\\\\\nparagraph[jumps]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown fox",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "jumps",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"escaped newline in list item",
+		"- Item o\\\nne\n- Item two",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item two",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"condition",
+		"The quick if[draft]{brown bold[fox]} jumps",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeConditionTag,
+							Val: "draft",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "brown",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "fox",
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeText,
+							Val: "jumps",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"condition without block",
+		"The quick if[draft] fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeError,
+							Val: "Invalid condition: draft",
+						},
+						{
+							Typ: nodeText,
+							Val: "fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"variable",
+		"The quick var[colour] fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeVariableTag,
+							Val: "colour",
+						},
+						{
+							Typ: nodeText,
+							Val: "fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"variable without name",
+		"The quick var[] fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick",
+						},
+						{
+							Typ: nodeError,
+							Val: "Invalid variable: ",
+						},
+						{
+							Typ: nodeText,
+							Val: "fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"heading w/ only whitespace",
+		". ",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingOne,
+					Val: ".",
+				},
+			},
+		},
+	},
+	{
+		"heading w/ only newline and paragraph underneath",
+		".\nThe quick brown fox",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingOne,
+					Val: ".",
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick brown fox",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"heading only at EOF",
+		".",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingOne,
+					Val: ".",
+				},
+			},
+		},
+	},
+	{
+		"heading ending in tag at EOF",
+		": The bold[quick]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingTwo,
+					Val: ":",
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The",
+						},
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "quick",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"heading ending in unclosed tag at EOF",
+		":. The italic[quick",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeHeadingThree,
+					Val: ":.",
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The",
+						},
+						{
+							Typ: nodeItalicTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "quick",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list starting input w/ nested item",
+		"- Item one\n  - Item two",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"ordered list nested in unordered list",
+		"- Item one\n  1. Item two\n  2. Item three\n- Item four",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeOrderedList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+											},
+										},
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item three",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item four",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"task items nested in unordered list",
+		"- Item one\n  - [ ] Item two\n  - [x] Item bold[three]\n  - [X]\n- Item [x] four",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ:  nodeListItem,
+											Task: true,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+											},
+										},
+										{
+											Typ:     nodeListItem,
+											Task:    true,
+											Checked: true,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item",
+												},
+												{
+													Typ: nodeBoldTag,
+													Children: []*Node{
+														{
+															Typ: nodeText,
+															Val: "three",
+														},
+													},
+												},
+											},
+										},
+										{
+											Typ:     nodeListItem,
+											Task:    true,
+											Checked: true,
+										},
+									},
+								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item [x] four",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"ordered list followed by unordered list",
+		"1. Item one\n- Item two",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeOrderedList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+							},
+						},
+					},
+				},
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item two",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"list w/ mixed tab and space indents",
+		"- Item one\n\t- Item two\n    - Item three\n  \t- Item four",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Item one",
+								},
+								{
+									Typ: nodeList,
+									Children: []*Node{
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item two",
+												},
+											},
+										},
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item three",
+												},
+											},
+										},
+										{
+											Typ: nodeListItem,
+											Children: []*Node{
+												{
+													Typ: nodeText,
+													Val: "Item four",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"quote with two paragraphs followed by paragraph",
+		"> Line one\n> Line two\n>\n> Line three\nParagraph",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeBlockquote,
+					Children: []*Node{
+						{
+							Typ: nodeParagraph,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Line one Line two",
+								},
+							},
+						},
+						{
+							Typ: nodeParagraph,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "Line three",
+								},
+							},
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Paragraph",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"paragraph tag between text",
+		"Intro paragraph[Grouped bold[text]] outro",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Intro",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Grouped",
+						},
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "text",
+								},
+							},
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "outro",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"consecutive paragraph tags",
+		"paragraph[One]paragraph[Two]\\\nparagraph[Three]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "One",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Two",
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Three",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"paragraph tag inside a tag",
+		"bold[paragraph[text]]",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeError,
+									Val: "Invalid tag name: paragraph",
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "text",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"rule between paragraphs",
+		"Roses\n---\nViolets",
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Roses",
+						},
+					},
+				},
+				{
+					Typ: nodeRule,
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Violets",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func checkChildren(parsedChildren, expectedChildren []*Node) bool {
+	if len(parsedChildren) != len(expectedChildren) {
+		return false
+	}
+
+	for i, childNode := range parsedChildren {
+		if childNode.Typ != expectedChildren[i].Typ {
+			return false
+		}
+		if childNode.Val != expectedChildren[i].Val {
+			return false
+		}
+		if childNode.Number != expectedChildren[i].Number || childNode.Start != expectedChildren[i].Start {
+			return false
+		}
+		if len(childNode.Children) > 0 {
+			if !checkChildren(childNode.Children, expectedChildren[i].Children) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func treesAreEqual(parsedTree, expectedTree *Node) bool {
+	if parsedTree.Typ != expectedTree.Typ {
+		return false
+	}
+	if parsedTree.Val != expectedTree.Val {
+		return false
+	}
+	if parsedTree.Task != expectedTree.Task || parsedTree.Checked != expectedTree.Checked {
+		return false
+	}
+	return checkChildren(parsedTree.Children, expectedTree.Children)
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range parseTests {
+		testParser := New()
+		parsedTree := testParser.Parse(test.input)
+		if !treesAreEqual(parsedTree, test.expectedTree) {
+			expectedTreeJSON, _ := json.MarshalIndent(test.expectedTree, "", "  ")
+			parsedTreeJSON, _ := json.MarshalIndent(parsedTree, "", "  ")
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, string(expectedTreeJSON), string(parsedTreeJSON))
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
+
+func TestParseTabIndents(t *testing.T) {
+	for _, test := range indentTests {
+		testParser := New(test.opts...)
+		tabTree := testParser.Parse(test.tabIndented)
+		spaceTree := testParser.Parse(test.spaceIndented)
+		if !treesAreEqual(tabTree, spaceTree) {
+			spaceTreeJSON, _ := json.MarshalIndent(spaceTree, "", "  ")
+			tabTreeJSON, _ := json.MarshalIndent(tabTree, "", "  ")
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, string(spaceTreeJSON), string(tabTreeJSON))
+		}
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	for _, test := range parseTests {
+		parsedTree, err := New().ParseReader(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s ERROR\n%v", test.name, err)
+			continue
+		}
+		if !treesAreEqual(parsedTree, test.expectedTree) {
+			expectedTreeJSON, _ := json.MarshalIndent(test.expectedTree, "", "  ")
+			parsedTreeJSON, _ := json.MarshalIndent(parsedTree, "", "  ")
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, string(expectedTreeJSON), string(parsedTreeJSON))
+		}
+	}
+
+	readErr := errors.New("read failed")
+	if tree, err := New().ParseReader(iotest.ErrReader(readErr)); !errors.Is(err, readErr) || tree != nil {
+		t.Errorf("read error ERROR\nexpected: %v\nreceived: %v, %v", readErr, tree, err)
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	tree := New().Parse("The quick fox\n\n: Heading two\n\n- Item foo[bar] link(url)[x]")
+	tests := []struct {
+		name         string
+		node         *Node
+		expectedLine int
+		expectedPos  int
+	}{
+		{"paragraph", tree.Children[0], 1, 0},
+		{"heading", tree.Children[1], 3, 15},
+		{"heading text", tree.Children[1].Children[0], 3, 17},
+		{"list", tree.Children[2], 5, 30},
+		{"error node", tree.Children[2].Children[0].Children[1], 5, 37},
+		{"error node text", tree.Children[2].Children[0].Children[1].Children[0], 5, 41},
+		{"link", tree.Children[2].Children[0].Children[2], 5, 46},
+	}
+
+	for _, test := range tests {
+		if test.node.Line != test.expectedLine || test.node.Pos != test.expectedPos {
+			t.Errorf("%s ERROR\nexpected: line %d, pos %d\nreceived: line %d, pos %d", test.name, test.expectedLine, test.expectedPos, test.node.Line, test.node.Pos)
+		}
+	}
+}
+
+func TestParseChecked(t *testing.T) {
+	input := "The quick foo[brown] fox\n\n.. Jumps over\nthe lazy ünï bar[dog]"
+	_, errs := New().ParseChecked(input)
+	expectedErrors := []string{
+		"1:11: Invalid tag name: foo",
+		"3:1: Invalid heading value: ..",
+		"4:14: Invalid tag name: bar",
+	}
+	if len(errs) != len(expectedErrors) {
+		t.Fatalf("expected: %d errors\nreceived: %v", len(expectedErrors), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expectedErrors[i] {
+			t.Errorf("expected: %s\nreceived: %s", expectedErrors[i], err)
+		}
+	}
+
+	var parseErr *ParseError
+	if !errors.As(errs[1], &parseErr) || parseErr.Code != "invalid_heading" || parseErr.Line != 3 || parseErr.Pos != 26 {
+		t.Errorf("expected: invalid_heading at line 3, pos 26\nreceived: %#v", errs[1])
+	}
+
+	if _, errs := New().ParseChecked("The quick bold[fox]"); errs != nil {
+		t.Errorf("expected: no errors\nreceived: %v", errs)
+	}
+}
+
+type parseOptionTest struct {
+	name         string
+	input        string
+	opts         []Option
+	expectedTree *Node
+}
+
+var parseOptionTests = []parseOptionTest{
+	{
+		"ordered list continuation",
+		"1. a\n\npara\n\n2. b",
+		[]Option{WithOrderedListContinuation()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeOrderedList,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
 								{
-									Typ: nodeList,
-									Children: []*Node{
-										{
-											Typ: nodeListItem,
-											Children: []*Node{
-												{
-													Typ: nodeText,
-													Val: "Item three",
-												},
-											},
-										},
-										{
-											Typ: nodeList,
-											Children: []*Node{
-												{
-													Typ: nodeListItem,
-													Children: []*Node{
-														{
-															Typ: nodeText,
-															Val: "Item four",
-														},
-													},
-												},
-											},
-										},
-									},
+									Typ: nodeText,
+									Val: "a",
 								},
+							},
+						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "para",
+						},
+					},
+				},
+				{
+					Typ:   nodeOrderedList,
+					Start: 2,
+					Children: []*Node{
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
 								{
-									Typ: nodeListItem,
-									Children: []*Node{
-										{
-											Typ: nodeText,
-											Val: "Item five",
-										},
-									},
+									Typ: nodeText,
+									Val: "b",
 								},
 							},
 						},
@@ -404,106 +2364,335 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"list with indents v3",
-		`
-        - Item one
-          - Item two
-            - Item three
-          - Item four
-        - Item five
-    `,
+		"ordered list numbers renumbered",
+		"1. a\n3. b\n7. c",
+		nil,
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeOrderedList,
 					Children: []*Node{
 						{
 							Typ: nodeListItem,
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item one",
+									Val: "a",
 								},
 							},
 						},
 						{
-							Typ: nodeList,
+							Typ: nodeListItem,
 							Children: []*Node{
 								{
-									Typ: nodeListItem,
-									Children: []*Node{
-										{
-											Typ: nodeText,
-											Val: "Item two",
-										},
-									},
+									Typ: nodeText,
+									Val: "b",
 								},
+							},
+						},
+						{
+							Typ: nodeListItem,
+							Children: []*Node{
 								{
-									Typ: nodeList,
-									Children: []*Node{
-										{
-											Typ: nodeListItem,
-											Children: []*Node{
-												{
-													Typ: nodeText,
-													Val: "Item three",
-												},
-											},
-										},
-									},
+									Typ: nodeText,
+									Val: "c",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"ordered list numbers from source",
+		"1. a\n3. b\n7. c",
+		[]Option{WithListStartFromSource()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeOrderedList,
+					Children: []*Node{
+						{
+							Typ:    nodeListItem,
+							Number: 1,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "a",
 								},
+							},
+						},
+						{
+							Typ:    nodeListItem,
+							Number: 3,
+							Children: []*Node{
 								{
-									Typ: nodeListItem,
-									Children: []*Node{
-										{
-											Typ: nodeText,
-											Val: "Item four",
-										},
-									},
+									Typ: nodeText,
+									Val: "b",
 								},
 							},
 						},
 						{
-							Typ: nodeListItem,
+							Typ:    nodeListItem,
+							Number: 7,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "c",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"auto links",
+		"See https://example.com, or https://example.org.",
+		[]Option{WithAutoLinks()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "See",
+						},
+						{
+							Typ: nodeLinkTag,
+							Val: "https://example.com",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "https://example.com",
+								},
+							},
+						},
+						{
+							Typ:    nodeText,
+							Val:    ", or",
+							Joined: true,
+						},
+						{
+							Typ: nodeLinkTag,
+							Val: "https://example.org",
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "https://example.org",
+								},
+							},
+						},
+						{
+							Typ:    nodeText,
+							Val:    ".",
+							Joined: true,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"auto link in parentheses",
+		"The fox (https://example.com/fox).",
+		[]Option{WithAutoLinks()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The fox (",
+						},
+						{
+							Typ:    nodeLinkTag,
+							Val:    "https://example.com/fox",
+							Joined: true,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "https://example.com/fox",
+								},
+							},
+						},
+						{
+							Typ:    nodeText,
+							Val:    ").",
+							Joined: true,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"preserved spaces",
+		"The quick     brown  bold[fox   jumps]   over",
+		[]Option{WithPreserveSpaces()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The quick     brown",
+						},
+						{
+							Typ: nodeBoldTag,
+							Children: []*Node{
+								{
+									Typ: nodeText,
+									Val: "fox   jumps",
+								},
+							},
+						},
+						{
+							Typ:    nodeText,
+							Val:    "   over",
+							Joined: true,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"hard line breaks",
+		"Roses are red\nViolets are bold[blue\nSugar] is sweet",
+		[]Option{WithHardLineBreaks()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "Roses are red",
+						},
+						{
+							Typ: nodeLineBreak,
+						},
+						{
+							Typ: nodeText,
+							Val: "Violets are",
+						},
+						{
+							Typ: nodeBoldTag,
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item five",
+									Val: "blue",
+								},
+								{
+									Typ: nodeLineBreak,
+								},
+								{
+									Typ: nodeText,
+									Val: "Sugar",
 								},
 							},
 						},
+						{
+							Typ: nodeText,
+							Val: "is sweet",
+						},
 					},
 				},
 			},
 		},
 	},
 	{
-		"list with paragraph underneath",
-		" - a\nb",
+		"consecutive hard line breaks",
+		"Roses are red\\\\\n\\\\\nViolets are blue",
+		[]Option{WithHardLineBreaks()},
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
+							Typ: nodeText,
+							Val: "Roses are red",
+						},
+						{
+							Typ: nodeLineBreak,
+						},
+						{
+							Typ: nodeLineBreak,
+						},
+						{
+							Typ: nodeText,
+							Val: "Violets are blue",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"custom tag",
+		"The spoiler[quick bold[fox]]",
+		[]Option{WithCustomTag("spoiler", "<span>", "</span>")},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
+						{
+							Typ: nodeText,
+							Val: "The",
+						},
+						{
+							Typ: nodeCustomTag,
+							Val: "spoiler",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "a",
+									Val: "quick",
+								},
+								{
+									Typ: nodeBoldTag,
+									Children: []*Node{
+										{
+											Typ: nodeText,
+											Val: "fox",
+										},
+									},
 								},
 							},
 						},
 					},
 				},
+			},
+		},
+	},
+	{
+		"paragraph merging w/ single blank line",
+		"The quick brown fox\n\njumps over the lazy dog",
+		[]Option{WithParagraphMerging()},
+		&Node{
+			Typ: nodeRoot,
+			Children: []*Node{
 				{
 					Typ: nodeParagraph,
 					Children: []*Node{
 						{
 							Typ: nodeText,
-							Val: "b",
+							Val: "The quick brown fox jumps over the lazy dog",
 						},
 					},
 				},
@@ -511,34 +2700,27 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"list items string literal",
-		`
-	       - Item one
-	       - Item two
-	  `,
+		"paragraph merging w/ two blank lines",
+		"The quick brown fox\n\n\njumps over the lazy dog",
+		[]Option{WithParagraphMerging()},
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "Item one",
-								},
-							},
+							Typ: nodeText,
+							Val: "The quick brown fox",
 						},
+					},
+				},
+				{
+					Typ: nodeParagraph,
+					Children: []*Node{
 						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "Item two",
-								},
-							},
+							Typ: nodeText,
+							Val: "jumps over the lazy dog",
 						},
 					},
 				},
@@ -546,35 +2728,37 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"list items string literal v2",
-		`
-	       - Item one
-           - Item two
-	  `,
+		"strikethrough shorthand",
+		"~~gone~~ and bold[~~also gone~~]",
+		[]Option{WithStrikethroughShorthand()},
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
+							Typ: nodeStrikeTag,
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "Item one",
+									Val: "gone",
 								},
 							},
 						},
 						{
-							Typ: nodeList,
+							Typ: nodeText,
+							Val: "and",
+						},
+						{
+							Typ: nodeBoldTag,
 							Children: []*Node{
 								{
-									Typ: nodeListItem,
+									Typ: nodeStrikeTag,
 									Children: []*Node{
 										{
 											Typ: nodeText,
-											Val: "Item two",
+											Val: "also gone",
 										},
 									},
 								},
@@ -586,86 +2770,52 @@ var parseTests = []parseTest{
 		},
 	},
 	{
-		"rich text over 2 list items",
-		"- The bold[quick\n- brown fox] jumps",
+		"stable error format w/ invalid tag",
+		"The quick foo[brown fox] jumps",
+		[]Option{WithStableErrorFormat()},
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeParagraph,
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "The",
-								},
-								{
-									Typ: nodeBoldTag,
-									Children: []*Node{
-										{
-											Typ: nodeText,
-											Val: "quick",
-										},
-									},
-								},
-							},
+							Typ: nodeText,
+							Val: "The quick",
 						},
 						{
-							Typ: nodeListItem,
+							Typ: nodeError,
+							Val: "invalid_tag|Invalid tag name|foo",
 							Children: []*Node{
 								{
 									Typ: nodeText,
-									Val: "brown fox jumps",
+									Val: "brown fox",
 								},
 							},
 						},
+						{
+							Typ: nodeText,
+							Val: "jumps",
+						},
 					},
 				},
 			},
 		},
 	},
 	{
-		"rich text over 2 list items v2",
-		"- The italic[bold[quick\n- brown fox] jumps",
+		"stable error format w/ invalid heading",
+		".. The quick brown fox",
+		[]Option{WithStableErrorFormat()},
 		&Node{
 			Typ: nodeRoot,
 			Children: []*Node{
 				{
-					Typ: nodeList,
+					Typ: nodeError,
+					Val: "invalid_heading|Invalid heading value|..",
 					Children: []*Node{
 						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "The",
-								},
-								{
-									Typ: nodeItalicTag,
-									Children: []*Node{
-										{
-											Typ: nodeBoldTag,
-											Children: []*Node{
-												{
-													Typ: nodeText,
-													Val: "quick",
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-						{
-							Typ: nodeListItem,
-							Children: []*Node{
-								{
-									Typ: nodeText,
-									Val: "brown fox jumps",
-								},
-							},
+							Typ: nodeText,
+							Val: "The quick brown fox",
 						},
 					},
 				},
@@ -674,46 +2824,153 @@ var parseTests = []parseTest{
 	},
 }
 
-func checkChildren(parsedChildren, expectedChildren []*Node) bool {
-	if len(parsedChildren) != len(expectedChildren) {
-		return false
-	}
-
-	for i, childNode := range parsedChildren {
-		if childNode.Typ != expectedChildren[i].Typ {
-			return false
-		}
-		if childNode.Val != expectedChildren[i].Val {
-			return false
-		}
-		if len(childNode.Children) > 0 {
-			if !checkChildren(childNode.Children, expectedChildren[i].Children) {
-				return false
-			}
+func TestParseWithOptions(t *testing.T) {
+	for _, test := range parseOptionTests {
+		testParser := New(test.opts...)
+		parsedTree := testParser.Parse(test.input)
+		if !treesAreEqual(parsedTree, test.expectedTree) {
+			expectedTreeJSON, _ := json.MarshalIndent(test.expectedTree, "", "  ")
+			parsedTreeJSON, _ := json.MarshalIndent(parsedTree, "", "  ")
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, string(expectedTreeJSON), string(parsedTreeJSON))
+			continue
 		}
+		t.Log(test.name, "OK")
 	}
+}
 
-	return true
+type diagnosticTest struct {
+	name                string
+	input               string
+	expectedDiagnostics []Diagnostic
 }
 
-func treesAreEqual(parsedTree, expectedTree *Node) bool {
-	if parsedTree.Typ != expectedTree.Typ {
-		return false
-	}
-	if parsedTree.Val != expectedTree.Val {
-		return false
-	}
-	return checkChildren(parsedTree.Children, expectedTree.Children)
+var diagnosticTests = []diagnosticTest{
+	{
+		"closed tag",
+		"The quick bold[brown fox] jumps over the lazy dog",
+		nil,
+	},
+	{
+		"unclosed tag absorbing the document",
+		"bold[The quick brown fox jumps over the lazy dog\nLorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "bold", Line: 1, Pos: 4},
+		},
+	},
+	{
+		"unclosed nested tags",
+		"The quick\nitalic[brown bold[fox jumps",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "bold", Line: 2, Pos: 27},
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "italic", Line: 2, Pos: 16},
+		},
+	},
+	{
+		"unclosed tag over 2 list items",
+		"- The bold[quick\n- brown fox] jumps",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "bold", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"unclosed tag over 3 list items",
+		"- The bold[quick\n- brown\n- fox] jumps",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "bold", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"unclosed condition",
+		"The quick if[draft]{brown fox\n\njumps over the lazy dog",
+		[]Diagnostic{
+			{Code: "unclosed_condition", Message: "Unclosed condition", Detail: "draft", Line: 1, Pos: 19},
+		},
+	},
+	{
+		"condition cut short",
+		"The quick if[draft]{brown fox\n\njumps over} the lazy dog",
+		[]Diagnostic{
+			{Code: "cut_condition", Message: "Condition cut short", Detail: "draft", Line: 2, Pos: 30},
+		},
+	},
+	{
+		"invalid tag",
+		"The quick foo[brown fox] jumps",
+		[]Diagnostic{
+			{Code: "invalid_tag", Message: "Invalid tag name", Detail: "foo", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"invalid heading",
+		".. The quick brown fox",
+		[]Diagnostic{
+			{Code: "invalid_heading", Message: "Invalid heading value", Detail: "..", Line: 1, Pos: 0},
+		},
+	},
+	{
+		"invalid headings on later lines",
+		".:. The quick fox\n- Item one\n\n:.. Jumps",
+		[]Diagnostic{
+			{Code: "invalid_heading", Message: "Invalid heading value", Detail: ".:.", Line: 1, Pos: 0},
+			{Code: "invalid_heading", Message: "Invalid heading value", Detail: ":..", Line: 4, Pos: 30},
+		},
+	},
+	{
+		"condition w/o block",
+		"The quick if[draft] brown fox",
+		[]Diagnostic{
+			{Code: "invalid_condition", Message: "Invalid condition", Detail: "draft", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"unknown variable",
+		"The quick var[colour] fox",
+		[]Diagnostic{
+			{Code: "unknown_variable", Message: "Unknown variable", Detail: "colour", Line: 1, Pos: 10},
+		},
+	},
+	{
+		"empty tag dropped",
+		"The bold[] fox",
+		nil,
+	},
+	{
+		"unclosed code",
+		"The code[fmt.Println",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "code", Line: 1, Pos: 8},
+		},
+	},
+	{
+		"unknown color",
+		"The color(red)[quick] fox",
+		[]Diagnostic{
+			{Code: "unknown_color", Message: "Unknown color", Detail: "red", Line: 1, Pos: 4},
+		},
+	},
+	{
+		"unclosed paragraph tag",
+		"paragraph[The quick\n\nbrown fox]",
+		[]Diagnostic{
+			{Code: "unclosed_tag", Message: "Unclosed tag", Detail: "paragraph", Line: 1, Pos: 9},
+		},
+	},
+	{
+		"paragraph tag in list item",
+		"- paragraph[Item]",
+		[]Diagnostic{
+			{Code: "invalid_tag", Message: "Invalid tag name", Detail: "paragraph", Line: 1, Pos: 2},
+		},
+	},
 }
 
-func TestParse(t *testing.T) {
-	for _, test := range parseTests {
+func TestDiagnostics(t *testing.T) {
+	for _, test := range diagnosticTests {
 		testParser := New()
-		parsedTree := testParser.Parse(test.input)
-		if !treesAreEqual(parsedTree, test.expectedTree) {
-			expectedTreeJSON, _ := json.MarshalIndent(test.expectedTree, "", "  ")
-			parsedTreeJSON, _ := json.MarshalIndent(parsedTree, "", "  ")
-			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, string(expectedTreeJSON), string(parsedTreeJSON))
+		testParser.Parse(test.input)
+		diagnostics := testParser.Diagnostics()
+		if !slices.Equal(diagnostics, test.expectedDiagnostics) {
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, test.expectedDiagnostics, diagnostics)
 			continue
 		}
 		t.Log(test.name, "OK")
@@ -0,0 +1,34 @@
+package runic
+
+// NodePathAt returns the types of the nodes containing the given byte offset in
+// the input, from the root down to the innermost node, e.g. `["Root", "List",
+// "ListItem", "BoldTag", "Text"]`. whitespace between two nodes belongs to
+// their parent
+func (p *parser) NodePathAt(input string, pos int) []string {
+	n := p.Parse(input)
+	path := []string{n.Typ}
+	for {
+		child := childAt(n, pos)
+		if child == nil {
+			return path
+		}
+		path = append(path, child.Typ)
+		n = child
+	}
+}
+
+// childAt returns the child of n containing the given byte offset, or nil if
+// there isn't one. the range of a node may overlap the whitespace at the start
+// of the next, so the last child beginning at or before the offset is chosen
+func childAt(n *Node, pos int) *Node {
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		child := n.Children[i]
+		if child.Pos <= pos {
+			if pos < child.end {
+				return child
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package runic
+
+import (
+	"slices"
+	"testing"
+)
+
+type nodePathTest struct {
+	name         string
+	input        string
+	pos          int
+	expectedPath []string
+}
+
+var nodePathTests = []nodePathTest{
+	{
+		"bold tag in nested list",
+		"- Item one\n  - The bold[quick brown] fox",
+		27,
+		[]string{nodeRoot, nodeList, nodeListItem, nodeList, nodeListItem, nodeBoldTag, nodeText},
+	},
+	{
+		"tag name",
+		"The bold[quick] fox",
+		5,
+		[]string{nodeRoot, nodeParagraph, nodeBoldTag},
+	},
+	{
+		"space after tag",
+		"The bold[quick] fox",
+		15,
+		[]string{nodeRoot, nodeParagraph},
+	},
+	{
+		"text on second line of paragraph",
+		"The quick\nbrown fox",
+		12,
+		[]string{nodeRoot, nodeParagraph, nodeText},
+	},
+	{
+		"blank line",
+		"The quick\n\nbrown fox",
+		10,
+		[]string{nodeRoot},
+	},
+	{
+		"quote",
+		"> The italic[quick] fox",
+		14,
+		[]string{nodeRoot, nodeBlockquote, nodeParagraph, nodeItalicTag, nodeText},
+	},
+	{
+		"past the end",
+		"The quick fox",
+		100,
+		[]string{nodeRoot},
+	},
+}
+
+func TestNodePathAt(t *testing.T) {
+	for _, test := range nodePathTests {
+		path := New().NodePathAt(test.input, test.pos)
+		if !slices.Equal(path, test.expectedPath) {
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, test.expectedPath, path)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package runic
+
+import "sync"
+
+// Pool reuses parsers between calls, so a service can render many documents
+// concurrently without allocating a parser for each one. it is safe for
+// concurrent use
+type Pool struct {
+	parsers sync.Pool
+}
+
+// NewPool returns a pool of parsers configured with the given options
+func NewPool(opts ...Option) *Pool {
+	cfg := newConfig(opts...)
+	return &Pool{
+		parsers: sync.Pool{
+			New: func() any {
+				return &parser{config: cfg}
+			},
+		},
+	}
+}
+
+// Html renders the input to HTML using a parser from the pool. the parser is
+// reset before it is returned, so no state is shared between calls
+func (pool *Pool) Html(input string) string {
+	p := pool.parsers.Get().(*parser)
+	defer pool.put(p)
+	return p.Html(input)
+}
+
+func (pool *Pool) put(p *parser) {
+	p.Reset()
+	pool.parsers.Put(p)
+}
+
+// Reset clears the state left behind by the last `Parse`, keeping the parser's
+// options and cache
+func (p *parser) Reset() {
+	p.tree = nil
+	p.lexer = nil
+	p.error = ""
+	p.currentNode = nil
+	p.ctx = 0
+	p.tagDepth = 0
+	p.conditionDepth = 0
+	p.strikeDepth = 0
+	p.droppedSquares = 0
+	p.collectedTokens = nil
+	p.diagnostics = nil
+	p.metadata = nil
+	p.lastOrderedList = nil
+}
@@ -0,0 +1,56 @@
+package runic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	testParser := New(WithOrderedListContinuation())
+	testParser.Parse("---\ntitle: Runic\n---\n1. The quick bold[brown fox jumps over the lazy dog")
+	testParser.Reset()
+
+	if testParser.tree != nil || testParser.lexer != nil || testParser.currentNode != nil {
+		t.Error("Reset kept the previous tree")
+	}
+	if testParser.tagDepth != 0 || len(testParser.collectedTokens) != 0 {
+		t.Error("Reset kept the previous parse state")
+	}
+	if diagnostics := testParser.Diagnostics(); len(diagnostics) != 0 {
+		t.Errorf("Reset kept the previous diagnostics: %v", diagnostics)
+	}
+	if len(testParser.metadata) != 0 || testParser.lastOrderedList != nil {
+		t.Error("Reset kept the previous frontmatter or ordered list")
+	}
+	if testParser.cache == nil {
+		t.Error("Reset removed the cache")
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	pool := NewPool(WithListDepth())
+	inputs := []string{
+		". This is a level one heading",
+		"The quick bold[brown fox jumps over the lazy dog",
+		"- Item one\n  - Item two",
+		"The quick brown fox\n\njumps over the lazy dog",
+		"italic[quick]",
+	}
+	var expectedHtml []string
+	for _, input := range inputs {
+		expectedHtml = append(expectedHtml, New(WithListDepth()).Html(input))
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := i % len(inputs)
+			if htmlString := pool.Html(inputs[n]); htmlString != expectedHtml[n] {
+				t.Errorf("%q ERROR\nexpected: %s\nreceived: %s", inputs[n], expectedHtml[n], htmlString)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,66 @@
+package runic
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// slugify turns text into a slug for a heading id, e.g. "This is a Heading!"
+// into "this-is-a-heading". letters and digits in any script are kept and
+// lowercased, runs of whitespace and hyphens become a single hyphen, and
+// everything else is left out
+func slugify(text string) string {
+	var slug strings.Builder
+	hyphen := false
+	for _, char := range text {
+		switch {
+		case unicode.IsLetter(char) || unicode.IsDigit(char):
+			if hyphen && slug.Len() > 0 {
+				slug.WriteRune(charHyphen)
+			}
+			hyphen = false
+			slug.WriteRune(unicode.ToLower(char))
+		case unicode.IsSpace(char) || char == charHyphen:
+			hyphen = true
+		}
+	}
+	return slug.String()
+}
+
+// uniqueID returns the slug of the given text, followed by `-1`, `-2` and so
+// on when it's already in `used`, and marks the result as used. text without
+// any letters or digits is given the slug "heading"
+func uniqueID(text string, used map[string]bool) string {
+	slug := slugify(text)
+	if slug == "" {
+		slug = "heading"
+	}
+	id := slug
+	for i := 1; used[id]; i++ {
+		id = slug + "-" + strconv.Itoa(i)
+	}
+	used[id] = true
+	return id
+}
+
+// truncateWords shortens text to at most `limit` characters, ending at the last
+// whitespace that fits so no word is cut in half. a first word longer than
+// `limit` is cut at the limit
+func truncateWords(text string, limit int) string {
+	runes := 0
+	for i, char := range text {
+		if runes < limit {
+			runes++
+			continue
+		}
+		if unicode.IsSpace(char) {
+			return text[:i]
+		}
+		if space := strings.LastIndexFunc(text[:i], unicode.IsSpace); space > 0 {
+			return text[:space]
+		}
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,58 @@
+package runic
+
+import "testing"
+
+type slugTest struct {
+	name         string
+	text         string
+	expectedSlug string
+}
+
+var slugTests = []slugTest{
+	{"words", "This is a level one heading", "this-is-a-level-one-heading"},
+	{"punctuation", "What's new? (v2.0)", "whats-new-v20"},
+	{"hyphens and spaces", "  Follow-up -  notes  ", "follow-up-notes"},
+	{"unicode", "Ünïcödé Überschrift 日本語", "ünïcödé-überschrift-日本語"},
+	{"no letters", "!!!", ""},
+}
+
+func TestSlugify(t *testing.T) {
+	for _, test := range slugTests {
+		if slug := slugify(test.text); slug != test.expectedSlug {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedSlug, slug)
+		}
+	}
+}
+
+type truncateWordsTest struct {
+	name          string
+	text          string
+	limit         int
+	expectedWords string
+}
+
+var truncateWordsTests = []truncateWordsTest{
+	{"shorter than limit", "The quick fox", 20, "The quick fox"},
+	{"cut between words", "The quick brown fox", 12, "The quick"},
+	{"word ending at limit", "The quick brown fox", 9, "The quick"},
+	{"long first word", "Supercalifragilistic fox", 5, "Super"},
+	{"unicode", "Ünïcödé Überschrift 日本語", 10, "Ünïcödé"},
+}
+
+func TestTruncateWords(t *testing.T) {
+	for _, test := range truncateWordsTests {
+		if words := truncateWords(test.text, test.limit); words != test.expectedWords {
+			t.Errorf("%s ERROR\nexpected: %q\nreceived: %q", test.name, test.expectedWords, words)
+		}
+	}
+}
+
+func TestUniqueID(t *testing.T) {
+	used := map[string]bool{}
+	expectedIDs := []string{"intro", "intro-1", "intro-2", "intro-1-1", "heading", "heading-1"}
+	for i, text := range []string{"Intro", "Intro", "intro!", "Intro 1", "", "?"} {
+		if id := uniqueID(text, used); id != expectedIDs[i] {
+			t.Errorf("%q ERROR\nexpected: %q\nreceived: %q", text, expectedIDs[i], id)
+		}
+	}
+}
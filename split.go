@@ -0,0 +1,141 @@
+package runic
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// splitLongParagraphs replaces each top level paragraph longer than `limit`
+// characters with several shorter ones. text is split at the last sentence end
+// that fits, or the last word boundary if there is none, while tags are moved
+// to the next paragraph whole
+func splitLongParagraphs(tree *Node, limit int) {
+	var children []*Node
+	for _, child := range tree.Children {
+		if child.Typ != nodeParagraph || textLength(child) <= limit {
+			children = append(children, child)
+			continue
+		}
+		children = append(children, splitParagraph(child, limit)...)
+	}
+
+	tree.Children = nil
+	for _, child := range children {
+		tree.AppendChild(child)
+	}
+}
+
+func splitParagraph(paragraph *Node, limit int) []*Node {
+	current := newNode(nodeParagraph, "")
+	paragraphs := []*Node{current}
+	length := 0
+
+	startParagraph := func() {
+		current = newNode(nodeParagraph, "")
+		paragraphs = append(paragraphs, current)
+		length = 0
+	}
+	separator := func(n *Node) int {
+		if length == 0 || n.Joined {
+			return 0
+		}
+		return 1
+	}
+
+	for _, child := range paragraph.Children {
+		if child.Typ != nodeText {
+			if length > 0 && length+separator(child)+textLength(child) > limit {
+				startParagraph()
+			}
+			length += separator(child) + textLength(child)
+			current.AppendChild(child)
+			continue
+		}
+
+		text := child.Val
+		for text != "" {
+			head, tail := splitText(text, limit-length-separator(child))
+			if head == "" && length > 0 {
+				startParagraph()
+				text = strings.TrimLeft(text, " ")
+				continue
+			}
+			// a single word longer than the limit is kept whole
+			if head == "" {
+				head, tail, _ = strings.Cut(text, " ")
+				tail = strings.TrimLeft(tail, " ")
+			}
+
+			length += separator(child) + utf8.RuneCountInString(head)
+			piece := newNode(nodeText, head)
+			piece.Line, piece.Pos = child.Line, child.Pos
+			piece.Joined = child.Joined && len(current.Children) > 0
+			current.AppendChild(piece)
+			if text = tail; text != "" {
+				startParagraph()
+			}
+		}
+	}
+
+	// each paragraph starts where its first node does, which for a split text
+	// node is the start of the text it was split from
+	paragraphs[0].Line, paragraphs[0].Pos = paragraph.Line, paragraph.Pos
+	for _, split := range paragraphs[1:] {
+		if len(split.Children) > 0 {
+			split.Line, split.Pos = split.Children[0].Line, split.Children[0].Pos
+		}
+	}
+	return paragraphs
+}
+
+// splitText splits the text into a head of at most `room` characters and the
+// remaining tail. head is empty when no boundary fits
+func splitText(text string, room int) (head, tail string) {
+	// the characters are counted as they're read, stopping once past `room`
+	sentenceEnd, wordEnd := -1, -1
+	runes := 0
+	for i, char := range text {
+		if runes > room {
+			break
+		}
+		runes++
+		// the boundary is the start of a run of spaces, which
+		// `WithPreserveSpaces` may keep
+		if char != ' ' || i == 0 || text[i-1] == ' ' {
+			continue
+		}
+		wordEnd = i
+		if strings.ContainsAny(text[i-1:i], ".!?") {
+			sentenceEnd = i
+		}
+	}
+
+	if runes <= room {
+		return text, ""
+	}
+
+	cut := sentenceEnd
+	if cut < 0 {
+		cut = wordEnd
+	}
+	if cut < 0 {
+		return "", text
+	}
+	return text[:cut], strings.TrimLeft(text[cut:], " ")
+}
+
+// textLength returns the number of characters of text in the node and its
+// descendants, counting a space between each pair of adjacent nodes
+func textLength(n *Node) int {
+	length := utf8.RuneCountInString(n.Val)
+	if n.Typ != nodeText && n.Typ != nodeCodeTag && n.Typ != nodeVariableTag {
+		length = 0
+	}
+	for i, child := range n.Children {
+		if i > 0 && !child.Joined {
+			length++
+		}
+		length += textLength(child)
+	}
+	return length
+}
@@ -0,0 +1,44 @@
+package runic
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DocumentStats holds counts describing a document, such as for showing a word
+// count in an editor
+type DocumentStats struct {
+	Words      int `json:"words"`      // runs of text separated by whitespace
+	Characters int `json:"characters"` // characters of text, leaving out whitespace and markup
+	Paragraphs int `json:"paragraphs"` // paragraphs with content, including those in quotes
+	Headings   int `json:"headings"`   // headings of any level
+	ListItems  int `json:"listItems"`  // items of ordered and unordered lists, at any depth
+}
+
+// Stats parses the input and counts the words, characters, and blocks in it.
+// words and characters are counted from the plain text given by `Node.Text`
+func (p *parser) Stats(input string) DocumentStats {
+	tree := p.Parse(input)
+	text := tree.Text()
+	stats := DocumentStats{Words: len(strings.Fields(text))}
+	for _, char := range text {
+		if !unicode.IsSpace(char) {
+			stats.Characters++
+		}
+	}
+
+	Walk(tree, func(n *Node) bool {
+		switch n.Typ {
+		case nodeParagraph:
+			if len(n.Children) > 0 {
+				stats.Paragraphs++
+			}
+		case nodeHeadingOne, nodeHeadingTwo, nodeHeadingThree, nodeHeadingFour, nodeHeadingFive, nodeHeadingSix:
+			stats.Headings++
+		case nodeListItem:
+			stats.ListItems++
+		}
+		return true
+	})
+	return stats
+}
@@ -0,0 +1,42 @@
+package runic
+
+import "testing"
+
+type statsTest struct {
+	name          string
+	input         string
+	expectedStats DocumentStats
+}
+
+var statsTests = []statsTest{
+	{
+		"heading, paragraphs and nested list",
+		". The quick fox\n\nThe bold[quick] brown\nfox jumps.\n\nOver code[the] lazy dog\n\n- Item one\n  - Item two\n    1. Item link(url)[three]\n- Item four",
+		DocumentStats{Words: 20, Characters: 78, Paragraphs: 2, Headings: 1, ListItems: 4},
+	},
+	{
+		"quote and line break",
+		"The quick \\\\\nbrown fox\n\n> jumps\n>\n> over",
+		DocumentStats{Words: 6, Characters: 25, Paragraphs: 3},
+	},
+	{
+		"whitespace only",
+		"  \n\n\t\n",
+		DocumentStats{},
+	},
+	{
+		"empty tags and items",
+		"bold[ ] italic[]\n\n-\n- Item",
+		DocumentStats{Words: 1, Characters: 4, ListItems: 2},
+	},
+}
+
+func TestStats(t *testing.T) {
+	for _, test := range statsTests {
+		if stats := New().Stats(test.input); stats != test.expectedStats {
+			t.Errorf("%s ERROR\nexpected: %+v\nreceived: %+v", test.name, test.expectedStats, stats)
+			continue
+		}
+		t.Log(test.name, "OK")
+	}
+}
@@ -0,0 +1,57 @@
+package runic
+
+// tagNames maps each tag node to the name it is written with. the `~~`
+// shorthand counts as `strike`
+var tagNames = map[string]string{
+	nodeBoldTag:      "bold",
+	nodeItalicTag:    "italic",
+	nodeUnderlineTag: "underline",
+	nodeKbdTag:       "kbd",
+	nodeMarkTag:      "mark",
+	nodeCodeTag:      tagCode,
+	nodeLinkTag:      tagLink,
+	nodeImageTag:     tagImage,
+	nodeColorTag:     tagColor,
+	nodeConditionTag: tagCondition,
+	nodeVariableTag:  tagVariable,
+	nodeStrikeTag:    "strike",
+}
+
+// invalidTagNames maps the code of each diagnostic caused by a misused tag to
+// the name of that tag. an invalid tag name is its own detail
+var invalidTagNames = map[string]string{
+	errorCodes[errInvalidCondition]: tagCondition,
+	errorCodes[errInvalidVariable]:  tagVariable,
+	errorCodes[errInvalidLink]:      tagLink,
+	errorCodes[errInvalidImage]:     tagImage,
+	errorCodes[errInvalidColor]:     tagColor,
+}
+
+// TagUsage returns the number of times each tag is used in the input, including
+// invalid and empty tags, which makes typos such as `itlaic[...]` easy to spot
+func (p *parser) TagUsage(input string) map[string]int {
+	usage := map[string]int{}
+	countTags(p.Parse(input), usage)
+
+	// error nodes don't keep their tag name, so it's taken from the diagnostics
+	for _, diagnostic := range p.Diagnostics() {
+		if diagnostic.Code == errorCodes[errInvalidTag] || diagnostic.Code == errorCodes[errEmptyTag] {
+			usage[diagnostic.Detail]++
+		} else if name, ok := invalidTagNames[diagnostic.Code]; ok {
+			usage[name]++
+		}
+	}
+	return usage
+}
+
+func countTags(n *Node, usage map[string]int) {
+	Walk(n, func(n *Node) bool {
+		if name, ok := tagNames[n.Typ]; ok {
+			usage[name]++
+		}
+		if n.Typ == nodeCustomTag {
+			usage[n.Val]++
+		}
+		return true
+	})
+}
@@ -0,0 +1,31 @@
+package runic
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestTagUsage(t *testing.T) {
+	input := "The bold[quick] italic[brown] fox\n\n- bold[jumps] over\n- the itlaic[lazy] dog if[] var[]"
+	expectedUsage := map[string]int{
+		"bold":   2,
+		"italic": 1,
+		"itlaic": 1,
+		"if":     1,
+		"var":    1,
+	}
+	if usage := New().TagUsage(input); !maps.Equal(usage, expectedUsage) {
+		t.Errorf("expected: %v\nreceived: %v", expectedUsage, usage)
+	}
+}
+
+func TestTagUsageWithCustomTag(t *testing.T) {
+	input := "The spoiler[quick] bold[spoiler[brown]] fox"
+	expectedUsage := map[string]int{
+		"spoiler": 2,
+		"bold":    1,
+	}
+	if usage := New(WithCustomTag("spoiler", "<span>", "</span>")).TagUsage(input); !maps.Equal(usage, expectedUsage) {
+		t.Errorf("expected: %v\nreceived: %v", expectedUsage, usage)
+	}
+}
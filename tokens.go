@@ -0,0 +1,33 @@
+package runic
+
+import "strings"
+
+// Token is a single lexeme of the input, as read by the parser. it suits tools
+// which only need the structure of the markup, such as bracket matching
+type Token struct {
+	Type   string `json:"type"`             // e.g. "Text", "Tag" or "OpeningSquare"
+	Val    string `json:"val"`              // characters comprising the token, with whitespace collapsed
+	Line   int    `json:"line"`             // line where the token was found
+	Pos    int    `json:"pos"`              // byte offset in the input where the token was found
+	Indent int    `json:"indent,omitempty"` // width of the indentation before a list marker
+}
+
+// Tokens lexes the input with the parser's options and returns its tokens in
+// order, without the one marking the end of the input
+func (p *parser) Tokens(input string) []Token {
+	lexer := lex(input, p.config)
+	tokens := []Token{}
+	for lexer.nextToken() {
+		if lexer.token.Typ == typeEOF {
+			break
+		}
+		tokens = append(tokens, Token{
+			Type:   strings.TrimPrefix(lexer.token.Typ.String(), "type"),
+			Val:    lexer.token.Val,
+			Line:   lexer.token.Line,
+			Pos:    lexer.token.Pos,
+			Indent: lexer.token.indent,
+		})
+	}
+	return tokens
+}
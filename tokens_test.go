@@ -0,0 +1,58 @@
+package runic
+
+import (
+	"slices"
+	"testing"
+)
+
+type tokensTest struct {
+	name           string
+	input          string
+	opts           []Option
+	expectedTokens []Token
+}
+
+var tokensTests = []tokensTest{
+	{
+		"heading and rich text",
+		". The quick\nbrown link(https://example.com)[fox]",
+		nil,
+		[]Token{
+			{Type: "Heading", Val: ".", Line: 1, Pos: 0},
+			{Type: "Text", Val: "The quick", Line: 1, Pos: 2},
+			{Type: "Terminator", Val: "\n", Line: 1, Pos: 11},
+			{Type: "Text", Val: "brown", Line: 2, Pos: 12},
+			{Type: "Tag", Val: "link", Line: 2, Pos: 18},
+			{Type: "TagArg", Val: "https://example.com", Line: 2, Pos: 22},
+			{Type: "OpeningSquare", Val: "[", Line: 2, Pos: 43},
+			{Type: "Text", Val: "fox", Line: 2, Pos: 44},
+			{Type: "ClosingSquare", Val: "]", Line: 2, Pos: 47},
+		},
+	},
+	{
+		"nested list w/ tab size",
+		"- Item one\n\t- Item two",
+		[]Option{WithTabSize(2)},
+		[]Token{
+			{Type: "Bulletpoint", Val: "-", Line: 1, Pos: 0},
+			{Type: "Text", Val: "Item one", Line: 1, Pos: 2},
+			{Type: "Bulletpoint", Val: "-", Line: 2, Pos: 12, Indent: 2},
+			{Type: "Text", Val: "Item two", Line: 2, Pos: 14},
+		},
+	},
+	{
+		"empty input",
+		"",
+		nil,
+		[]Token{},
+	},
+}
+
+func TestTokens(t *testing.T) {
+	for _, test := range tokensTests {
+		tokens := New(test.opts...).Tokens(test.input)
+		if !slices.Equal(tokens, test.expectedTokens) {
+			t.Errorf("%s ERROR\nexpected: %v\nreceived: %v", test.name, test.expectedTokens, tokens)
+		}
+	}
+}